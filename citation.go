@@ -0,0 +1,269 @@
+package eprinttools
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CitationStyles lists the style names understood by Citation()
+var CitationStyles = []string{"apa", "mla", "chicago"}
+
+// initials turns given names into initials (e.g. "Robert S." -> "R. S.")
+func initials(given string) string {
+	parts := []string{}
+	for _, name := range strings.Fields(given) {
+		r, _ := utf8.DecodeRuneInString(name)
+		if unicode.IsLetter(r) {
+			parts = append(parts, string(r)+".")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinNames joins a list of names using sep, with final before the
+// last name (e.g. "A, B, & C").
+func joinNames(names []string, sep string, final string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + strings.TrimRight(final, " ") + " " + names[1]
+	}
+	return strings.Join(names[0:len(names)-1], sep) + final + names[len(names)-1]
+}
+
+// endSentence adds a period to s unless it already ends in a period,
+// question mark or exclamation mark
+func endSentence(s string) string {
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!") {
+		return s
+	}
+	return s + "."
+}
+
+// citationNames returns the names of the creators (or corporate
+// creators) for use in a citation.
+func (e *EPrint) citationNames() []*Name {
+	names := []*Name{}
+	if e.Creators != nil {
+		for _, item := range e.Creators.Items {
+			if item.Name != nil {
+				names = append(names, item.Name)
+			}
+		}
+	}
+	if len(names) == 0 && e.CorpCreators != nil {
+		for _, item := range e.CorpCreators.Items {
+			if item.Name != nil {
+				names = append(names, item.Name)
+			} else if item.Value != "" {
+				names = append(names, &Name{Value: item.Value})
+			}
+		}
+	}
+	return names
+}
+
+// citationYear returns the year from the EPrint's date
+func (e *EPrint) citationYear() string {
+	if len(e.Date) >= 4 {
+		return e.Date[0:4]
+	}
+	return ""
+}
+
+// citationContainer returns the journal or book the EPrint is part of
+func (e *EPrint) citationContainer() string {
+	switch {
+	case e.Publication != "":
+		return e.Publication
+	case e.BookTitle != "" && e.BookTitle != e.Title:
+		return e.BookTitle
+	}
+	return ""
+}
+
+// getDOI returns the EPrint's DOI, Caltech Library keeps the DOI
+// as the first related URL of type "doi".
+func (e *EPrint) getDOI() string {
+	doi := strings.TrimSpace(e.DOI)
+	if doi == "" && e.RelatedURL != nil {
+		for _, item := range e.RelatedURL.Items {
+			if strings.ToLower(item.Type) == "doi" && item.URL != "" {
+				doi = strings.TrimSpace(item.URL)
+				break
+			}
+		}
+	}
+	return doi
+}
+
+// citationDOI returns the DOI as a URL
+func (e *EPrint) citationDOI() string {
+	doi := e.getDOI()
+	if doi != "" && strings.Contains(doi, "://") == false {
+		doi = "https://doi.org/" + doi
+	}
+	return doi
+}
+
+// Citation renders the EPrint as a formatted plain text citation in
+// one of the CitationStyles ("apa", "mla" or "chicago" author-date).
+func (e *EPrint) Citation(style string) (string, error) {
+	var (
+		parts []string
+		names []string
+	)
+	creators := e.citationNames()
+	year := e.citationYear()
+	title := strings.TrimSpace(e.Title)
+	container := e.citationContainer()
+	doi := e.citationDOI()
+	if doi == "" {
+		doi = e.OfficialURL
+	}
+
+	switch strings.ToLower(style) {
+	case "apa":
+		for _, name := range creators {
+			if name.Family != "" {
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s, %s", name.Family, initials(name.Given))))
+			} else {
+				names = append(names, name.Value)
+			}
+		}
+		if s := joinNames(names, ", ", ", & "); s != "" {
+			parts = append(parts, strings.TrimSuffix(s, ".")+".")
+		}
+		if year == "" {
+			year = "n.d."
+		}
+		parts = append(parts, fmt.Sprintf("(%s).", year))
+		if title != "" {
+			parts = append(parts, endSentence(title))
+		}
+		if container != "" {
+			s := container
+			if e.Volume != "" {
+				s += ", " + e.Volume
+				if e.Number != "" {
+					s += fmt.Sprintf("(%s)", e.Number)
+				}
+			}
+			if e.PageRange != "" {
+				s += ", " + e.PageRange
+			}
+			parts = append(parts, s+".")
+		} else if e.Publisher != "" {
+			parts = append(parts, e.Publisher+".")
+		}
+		if doi != "" {
+			parts = append(parts, doi)
+		}
+	case "mla":
+		for i, name := range creators {
+			switch {
+			case name.Family == "":
+				names = append(names, name.Value)
+			case i == 0:
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s, %s", name.Family, name.Given)))
+			default:
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s %s", name.Given, name.Family)))
+			}
+		}
+		if len(names) > 2 {
+			names = []string{names[0] + ", et al"}
+		}
+		if s := joinNames(names, ", ", ", and "); s != "" {
+			parts = append(parts, strings.TrimSuffix(s, ".")+".")
+		}
+		if title != "" {
+			if container != "" {
+				parts = append(parts, "\""+endSentence(title)+"\"")
+			} else {
+				parts = append(parts, endSentence(title))
+			}
+		}
+		details := []string{}
+		if container != "" {
+			details = append(details, container)
+		} else if e.Publisher != "" {
+			details = append(details, e.Publisher)
+		}
+		if e.Volume != "" {
+			details = append(details, "vol. "+e.Volume)
+		}
+		if e.Number != "" {
+			details = append(details, "no. "+e.Number)
+		}
+		if year != "" {
+			details = append(details, year)
+		}
+		if e.PageRange != "" {
+			details = append(details, "pp. "+e.PageRange)
+		}
+		if len(details) > 0 {
+			parts = append(parts, strings.Join(details, ", ")+".")
+		}
+		if doi != "" {
+			parts = append(parts, doi+".")
+		}
+	case "chicago":
+		for i, name := range creators {
+			switch {
+			case name.Family == "":
+				names = append(names, name.Value)
+			case i == 0:
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s, %s", name.Family, name.Given)))
+			default:
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s %s", name.Given, name.Family)))
+			}
+		}
+		if len(names) > 10 {
+			names = append(names[0:7], "et al")
+			parts = append(parts, strings.Join(names, ", ")+".")
+		} else if s := joinNames(names, ", ", ", and "); s != "" {
+			parts = append(parts, strings.TrimSuffix(s, ".")+".")
+		}
+		if year == "" {
+			year = "n.d."
+		}
+		parts = append(parts, year+".")
+		if title != "" {
+			if container != "" {
+				parts = append(parts, "\""+endSentence(title)+"\"")
+			} else {
+				parts = append(parts, endSentence(title))
+			}
+		}
+		if container != "" {
+			s := container
+			if e.Volume != "" {
+				s += " " + e.Volume
+			}
+			if e.Number != "" {
+				s += fmt.Sprintf(" (%s)", e.Number)
+			}
+			if e.PageRange != "" {
+				s += ": " + e.PageRange
+			}
+			parts = append(parts, s+".")
+		} else if e.Publisher != "" {
+			if e.PlaceOfPub != "" {
+				parts = append(parts, fmt.Sprintf("%s: %s.", e.PlaceOfPub, e.Publisher))
+			} else {
+				parts = append(parts, e.Publisher+".")
+			}
+		}
+		if doi != "" {
+			parts = append(parts, doi+".")
+		}
+	default:
+		return "", fmt.Errorf("unknown citation style %q, expected one of %s", style, strings.Join(CitationStyles, ", "))
+	}
+	return strings.Join(parts, " "), nil
+}
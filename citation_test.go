@@ -0,0 +1,65 @@
+package eprinttools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCitation(t *testing.T) {
+	e := new(EPrint)
+	e.Title = "A study of things"
+	e.Date = "2019-03-02"
+	e.Publication = "Journal of Things"
+	e.Volume = "12"
+	e.Number = "3"
+	e.PageRange = "45-67"
+	e.DOI = "10.1234/things.2019"
+	e.Creators = new(CreatorItemList)
+	e.Creators.AddItem(&Item{Name: &Name{Family: "Doe", Given: "Jane"}})
+	e.Creators.AddItem(&Item{Name: &Name{Family: "Smith", Given: "Robert S."}})
+
+	expected := map[string]string{
+		"apa":     `Doe, J., & Smith, R. S. (2019). A study of things. Journal of Things, 12(3), 45-67. https://doi.org/10.1234/things.2019`,
+		"mla":     `Doe, Jane, and Robert S. Smith. "A study of things." Journal of Things, vol. 12, no. 3, 2019, pp. 45-67. https://doi.org/10.1234/things.2019.`,
+		"chicago": `Doe, Jane, and Robert S. Smith. 2019. "A study of things." Journal of Things 12 (3): 45-67. https://doi.org/10.1234/things.2019.`,
+	}
+	for _, style := range CitationStyles {
+		s, err := e.Citation(style)
+		if err != nil {
+			t.Errorf("Citation(%q) returned an error, %s", style, err)
+			continue
+		}
+		if s != expected[style] {
+			t.Errorf("Citation(%q) expected\n%s\ngot\n%s", style, expected[style], s)
+		}
+	}
+
+	e.Title = `The "things" \ a study`
+	s, err := e.Citation("mla")
+	if err != nil {
+		t.Errorf("Citation(%q) returned an error, %s", "mla", err)
+	} else if strings.Contains(s, `"The "things" \ a study."`) == false {
+		t.Errorf("expected title quoted without escapes, got %s", s)
+	}
+
+	e.Title = "What are things?"
+	for _, style := range CitationStyles {
+		s, err := e.Citation(style)
+		if err != nil {
+			t.Errorf("Citation(%q) returned an error, %s", style, err)
+		} else if strings.Contains(s, "things?.") || strings.Contains(s, "What are things?") == false {
+			t.Errorf("Citation(%q) expected the title's question mark to end it, got %s", style, s)
+		}
+	}
+	e.Title = "A study of things."
+	s, err = e.Citation("apa")
+	if err != nil {
+		t.Errorf("Citation(%q) returned an error, %s", "apa", err)
+	} else if strings.Contains(s, "A study of things. Journal") == false {
+		t.Errorf("expected a single period after the title, got %s", s)
+	}
+
+	if _, err := e.Citation("harvard"); err == nil {
+		t.Errorf("expected an error for an unknown citation style")
+	}
+}
@@ -2,5 +2,6 @@ package main
 
 var (
 
-    // Examples is a map to asset files associated with main package
-    Examples = map[string][]byte{
\ No newline at end of file
+	// Examples is a map to asset files associated with main package
+	Examples = map[string][]byte{}
+)
@@ -26,9 +26,13 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	// Caltech Library packages
 	"github.com/caltechlibrary/cli"
@@ -36,6 +40,7 @@ import (
 	"github.com/caltechlibrary/dataciteapi"
 	"github.com/caltechlibrary/eprinttools"
 	"github.com/caltechlibrary/eprinttools/clsrules"
+	"github.com/caltechlibrary/eprinttools/rc"
 )
 
 var (
@@ -60,9 +65,31 @@ Example generating an EPrintsXML for two DOI
 	%s "10.1021/acsami.7b15651" "10.1093/mnras/stu2495" > articles.xml
 
 Example processing a list of DOIs in a text file into
-an XML document called "import-articles.xml".
+an XML document called "import-articles.xml". The list has
+one DOI per line, blank lines and lines starting with "#"
+are skipped. A DOI that can't be retrieved or converted is
+reported and the rest of the list is processed.
 
 	%s -i doi-list.txt -o import-articles.xml
+
+Example checking the DOI against an existing repository
+before importing. Each DOI is looked up with the repository's
+search (see -doi-search), DOI already in the repository are
+reported and with -skip-duplicates left out of the XML.
+
+	%s -eprints-url https://example.org -skip-duplicates \
+	    -i doi-list.txt -o import-articles.xml
+
+Example adjusting how CrossRef types map to EPrint types.
+The rules file is JSON, entries in "types", "monograph_types"
+and "contributor_roles" are added to the defaults and
+"issn_type" picks the preferred ISSN (e.g. "electronic").
+CrossRef abstracts are converted from JATS to plain text,
+set "abstract_format" to "html" to keep paragraphs and
+inline formatting as HTML.
+
+	echo '{"types": {"posted-content": "preprint"}}' > rules.json
+	%s -crossref-rules rules.json "10.1101/2020.01.01.000001"
 `
 
 	license = `
@@ -99,8 +126,182 @@ THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
 	dataciteOnly                   bool
 	useCaltechLibrarySpecificRules bool
 	asJSON                         bool
+	delay                          time.Duration
+	caBundle                       string
+	clientCert                     string
+	clientKey                      string
+	skipDuplicates                 bool
+	credentials                    string
+	doiSearch                      string
+	crossRefRulesFName             string
+
+	// crossRefRules maps CrossRef works to EPrints
+	crossRefRules = eprinttools.DefaultCrossRefRules()
+
+	// tlsClient if not nil is used for the CrossRef and DataCite
+	// lookups, it is set by -ca-bundle, -client-cert and -client-key
+	tlsClient *http.Client
 )
 
+// worksResult holds a works record retrieved by getWorks along with
+// the response status and the rate limit the API advertised
+type worksResult struct {
+	Object     map[string]interface{}
+	Status     string
+	StatusCode int
+	Limit      int
+	Interval   int
+}
+
+// getWorks retrieves the works record for doi from apiURL (the CrossRef
+// or DataCite API) using tlsClient. The record is only decoded for a 200
+// response, the caller checks StatusCode.
+func getWorks(apiURL string, appName string, mailTo string, doi string) (*worksResult, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join("/works", eprinttools.NormalizeDOI(doi))
+	qry := u.Query()
+	qry.Set("mailto", mailTo)
+	u.RawQuery = qry.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", fmt.Sprintf("%s (mailto: %s)", appName, mailTo))
+	res, err := tlsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	result := &worksResult{
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+	}
+	if i, err := strconv.Atoi(res.Header.Get("X-Rate-Limit-Limit")); err == nil {
+		result.Limit = i
+	}
+	if i, err := strconv.Atoi(strings.TrimSuffix(res.Header.Get("X-Rate-Limit-Interval"), "s")); err == nil {
+		result.Interval = i
+	}
+	if res.StatusCode == 200 {
+		//NOTE: numbers are kept as json.Number like the API clients do
+		dec := json.NewDecoder(res.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&result.Object); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// lastRequest is when throttle last allowed an API request
+var lastRequest time.Time
+
+// throttle waits so API requests are at least delay apart, or further
+// apart if the rate limit (requests per interval seconds) advertised
+// by the API requires it.
+func throttle(limit, interval int) {
+	wait := delay
+	if limit > 0 && interval > 0 {
+		if d := (time.Duration(interval) * time.Second) / time.Duration(limit); d > wait {
+			wait = d
+		}
+	}
+	if elapsed := time.Since(lastRequest); elapsed < wait {
+		time.Sleep(wait - elapsed)
+	}
+	lastRequest = time.Now()
+}
+
+// crossRefToEPrint looks up doi in CrossRef, returns nil without an
+// error if the DOI isn't found.
+func crossRefToEPrint(api *crossrefapi.CrossRefClient, doi string) (*eprinttools.EPrint, error) {
+	var (
+		obj crossrefapi.Object
+		err error
+	)
+	throttle(api.RateLimitLimit, api.RateLimitInterval)
+	if tlsClient != nil {
+		var res *worksResult
+		if res, err = getWorks(api.API, api.AppName, api.MailTo, doi); err == nil {
+			obj, api.Status, api.StatusCode = res.Object, res.Status, res.StatusCode
+			if res.Limit > 0 && res.Interval > 0 {
+				api.RateLimitLimit, api.RateLimitInterval = res.Limit, res.Interval
+			}
+		}
+	} else {
+		obj, err = api.Works(doi)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ERROR (CrossRef API) %q, %s", doi, err)
+	}
+	if api.StatusCode != 200 {
+		return nil, nil
+	}
+	eprint, err := crossRefRules.WorksToEPrint(obj)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR (CrossRef to EPrintXML): skipping %q, %s", doi, err)
+	}
+	return eprint, nil
+}
+
+// dataCiteToEPrint looks up doi in DataCite, returns nil without an
+// error if the DOI isn't found.
+func dataCiteToEPrint(api *dataciteapi.DataCiteClient, doi string) (*eprinttools.EPrint, error) {
+	var (
+		obj dataciteapi.Object
+		err error
+	)
+	throttle(api.RateLimitLimit, api.RateLimitInterval)
+	if tlsClient != nil {
+		var res *worksResult
+		if res, err = getWorks(api.API, api.AppName, api.MailTo, doi); err == nil {
+			obj, api.Status, api.StatusCode = res.Object, res.Status, res.StatusCode
+			if res.Limit > 0 && res.Interval > 0 {
+				api.RateLimitLimit, api.RateLimitInterval = res.Limit, res.Interval
+			}
+		}
+	} else {
+		obj, err = api.Works(doi)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ERROR (DataCite API): %q, %s", doi, err)
+	}
+	if api.StatusCode != 200 {
+		return nil, nil
+	}
+	eprint, err := eprinttools.DataCiteWorksToEPrint(obj)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR (DataCite to EPrintXML): skipping %q, %s", doi, err)
+	}
+	return eprint, nil
+}
+
+// doiToEPrint looks up doi in CrossRef and if not found there in
+// DataCite (unless -crossref or -datacite are set).
+func doiToEPrint(apiCrossRef *crossrefapi.CrossRefClient, apiDataCite *dataciteapi.DataCiteClient, doi string) (*eprinttools.EPrint, error) {
+	if dataciteOnly == false {
+		eprint, err := crossRefToEPrint(apiCrossRef, doi)
+		if err != nil || eprint != nil {
+			return eprint, err
+		}
+		if crossrefOnly {
+			return nil, fmt.Errorf("WARNING (CrossRef API): %q, %s", doi, apiCrossRef.Status)
+		}
+	}
+	// NOTE: We try DataCite's API as a fallback when CrossRef fails...
+	eprint, err := dataCiteToEPrint(apiDataCite, doi)
+	if err != nil || eprint != nil {
+		return eprint, err
+	}
+	if dataciteOnly {
+		return nil, fmt.Errorf("WARNING (DataCite API): %q, %s", doi, apiDataCite.Status)
+	}
+	return nil, fmt.Errorf("WARNING: %s not found in CrossRef or DataCite API lookup", doi)
+}
+
 func main() {
 	appName := path.Base(os.Args[0])
 
@@ -111,7 +312,7 @@ func main() {
 		[]byte(fmt.Sprintf(eprinttools.LicenseText,
 			appName, eprinttools.Version)))
 	app.AddHelp("description", []byte(fmt.Sprintf(description, appName)))
-	app.AddHelp("examples", []byte(fmt.Sprintf(examples, appName, appName, appName)))
+	app.AddHelp("examples", []byte(fmt.Sprintf(examples, appName, appName, appName, appName, appName)))
 
 	// Standard Options
 	app.BoolVar(&showHelp, "h,help", false, "display help")
@@ -120,14 +321,23 @@ func main() {
 	app.BoolVar(&generateMarkdown, "generate-markdown", false, "generate Markdown documentation")
 	app.BoolVar(&generateManPage, "generate-manpage", false, "generate man page")
 	app.StringVar(&inputFName, "i,input", "", "set input filename")
+	app.StringVar(&outputFName, "o,output", "", "set output filename")
 	app.BoolVar(&quiet, "quiet", false, "set quiet output")
 
 	// Application Options
-	app.StringVar(&apiEPrintsURL, "eprints-url", "", "Sets the EPRints API URL")
+	app.StringVar(&apiEPrintsURL, "eprints-url", "", "Sets the EPrints API URL, DOI already in the repository are reported")
+	app.BoolVar(&skipDuplicates, "skip-duplicates", false, "leave out DOI already in the repository (requires -eprints-url)")
+	app.StringVar(&doiSearch, "doi-search", eprinttools.DefaultDOISearch, "EPrints search used to find DOI already in the repository, {doi} is replaced by the DOI")
+	app.StringVar(&credentials, "credentials", "", "read EPRINT_USERNAME and EPRINT_PASSWORD for -eprints-url from a JSON file (must be chmod 600)")
 	app.BoolVar(&crossrefOnly, "c,crossref", false, "only search CrossRef API for DOI records")
 	app.BoolVar(&dataciteOnly, "d,datacite", false, "only search DataCite API for DOI records")
 	app.BoolVar(&useCaltechLibrarySpecificRules, "clsrules", true, "Apply Caltech Library Specific Rules to EPrintXML output")
+	app.StringVar(&crossRefRulesFName, "crossref-rules", "", "read CrossRef type and contributor mapping rules from a JSON file")
 	app.BoolVar(&asJSON, "json", false, "output EPrint structure as JSON")
+	app.DurationVar(&delay, "delay", 100*time.Millisecond, "minimum time between API requests")
+	app.StringVar(&caBundle, "ca-bundle", "", "trust the PEM certificates in this file (e.g. a campus CA)")
+	app.StringVar(&clientCert, "client-cert", "", "PEM client certificate to present for TLS connections")
+	app.StringVar(&clientKey, "client-key", "", "PEM key for the client certificate")
 
 	//FIXME: Need to come up with a better way of setting this,
 	// perhaps a config mode and save the setting in
@@ -186,13 +396,25 @@ func main() {
 	cli.ExitOnError(app.Eout, err, quiet)
 	defer cli.CloseFile(inputFName, app.In)
 
+	if crossRefRulesFName != "" {
+		crossRefRules, err = eprinttools.LoadCrossRefRules(crossRefRulesFName)
+		cli.ExitOnError(app.Eout, err, quiet)
+	}
+
+	if caBundle != "" || clientCert != "" || clientKey != "" {
+		tlsClient, err = rc.ConfigureTLS(caBundle, clientCert, clientKey)
+		cli.ExitOnError(app.Eout, err, quiet)
+		tlsClient.Timeout = 30 * time.Second
+	}
+
 	if inputFName != "" {
 		src, err := ioutil.ReadAll(app.In)
 		cli.ExitOnError(app.Eout, err, quiet)
 		//FIXME: this bytes to string split is ugly...
 		for _, line := range strings.Split(fmt.Sprintf("%s", src), "\n") {
 			arg := strings.TrimSpace(line)
-			if len(arg) > 0 {
+			// NOTE: skip blank lines and comments
+			if len(arg) > 0 && strings.HasPrefix(arg, "#") == false {
 				args = append(args, arg)
 			}
 		}
@@ -204,116 +426,85 @@ func main() {
 	// query the dataciteapi
 	apiCrossRef, err := crossrefapi.NewCrossRefClient(appName, mailto)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+		fmt.Fprintf(app.Eout, "%s\n", err)
 		os.Exit(1)
 	}
 	apiDataCite, err := dataciteapi.NewDataCiteClient(appName, mailto)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+		fmt.Fprintf(app.Eout, "%s\n", err)
 		os.Exit(1)
 	}
 
+	// NOTE: search the repository for each DOI so we can
+	// avoid duplicate imports
+	var api *eprinttools.EPrintsAPI
+	if apiEPrintsURL != "" {
+		var (
+			authMethod, username, secret string
+		)
+		if credentials != "" {
+			m, err := eprinttools.LoadCredentials(credentials)
+			cli.ExitOnError(app.Eout, err, quiet)
+			username, secret = m["EPRINT_USERNAME"], m["EPRINT_PASSWORD"]
+			authMethod = "basic"
+		}
+		api, err = eprinttools.New(apiEPrintsURL, false, authMethod, username, secret)
+		cli.ExitOnError(app.Eout, err, quiet)
+		api.Client = tlsClient
+		api.DOISearch = doiSearch
+	}
+
 	//NOTE: need to support processing one or more DOI
+	errCnt := 0
 	for _, doi := range args {
-		switch {
-		case crossrefOnly:
-			obj, err := apiCrossRef.Works(doi)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR (CrossRef API) %q, %s\n", doi, err)
-				os.Exit(1)
-			}
-			if apiCrossRef.StatusCode == 200 {
-				// NOTE: First we see if we can get a CrossRef record
-				eprint, err := eprinttools.CrossRefWorksToEPrint(obj)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "ERROR (CrossRef to EPrintXML): skipping %q, %s\n", doi, err)
-				} else {
-					eprintsList.AddEPrint(eprint)
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "WARNING (CrossRef API): %q, %s\n", doi, apiCrossRef.Status)
-			}
-		case dataciteOnly:
-			obj, err := apiDataCite.Works(doi)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR (DataCite API): %q, %s\n", err)
-				os.Exit(1)
-			}
-			if apiDataCite.StatusCode == 200 {
-				eprint, err := eprinttools.DataCiteWorksToEPrint(obj)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "ERROR (DataCite to EPrintXML): skipping %q, %s\n", doi, err)
-				} else {
-					eprintsList.AddEPrint(eprint)
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "WARNING (DataCite API): %q, %s\n", doi, apiDataCite.Status)
-			}
-		default:
-			// NOTE: just done for readability for flagging failed lookups
-			isCrossRefDOI := false
-			isDataCiteDOI := false
-
-			obj, err := apiCrossRef.Works(doi)
+		if api != nil {
+			uris, err := api.FindDOI(doi)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "ERROR (CrossRef API): %q, %s\n", doi, err)
-				os.Exit(1)
+				fmt.Fprintf(app.Eout, "ERROR (EPrints): can't check %q, %s\n", doi, err)
+				errCnt++
+				continue
 			}
-			if apiCrossRef.StatusCode == 200 {
-				isCrossRefDOI = true
-				// NOTE: First we see if we can get a CrossRef record
-				eprint, err := eprinttools.CrossRefWorksToEPrint(obj)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "ERROR (CrossRef to EPrintXML): skipping %q, %s\n", doi, err)
-				} else {
-					eprintsList.AddEPrint(eprint)
+			if len(uris) > 0 {
+				fmt.Fprintf(app.Eout, "WARNING: %s already in repository as %s\n", doi, strings.Join(uris, ", "))
+				if skipDuplicates {
+					continue
 				}
 			}
-
-			// NOTE: We try DataCite's API as a fallback when CrossRef fials...
-			if isCrossRefDOI == false {
-				obj, err := apiDataCite.Works(doi)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "ERROR (DataCite API): %q, %s\n", doi, err)
-					os.Exit(1)
-				}
-				if apiDataCite.StatusCode == 200 {
-					isDataCiteDOI = true
-					eprint, err := eprinttools.DataCiteWorksToEPrint(obj)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "ERROR (DataCite to EPrintXML): skipping %q, %s\n", doi, err)
-					} else {
-						eprintsList.AddEPrint(eprint)
-					}
-				}
-			}
-			if isCrossRefDOI == false && isDataCiteDOI == false {
-				fmt.Fprintf(os.Stderr, "WARNING: %s not found in CrossRef or DataCite API lookup", doi)
-			}
 		}
+		eprint, err := doiToEPrint(apiCrossRef, apiDataCite, doi)
+		if err != nil {
+			fmt.Fprintf(app.Eout, "%s\n", err)
+			errCnt++
+			continue
+		}
+		eprintsList.AddEPrint(eprint)
+	}
+	if errCnt > 0 {
+		fmt.Fprintf(app.Eout, "WARNING: %d of %d DOI could not be converted\n", errCnt, len(args))
 	}
 	//FIXME: We need to apply Caltech Library Special Rules
 	// before marshaling our results...
 	if useCaltechLibrarySpecificRules {
 		eprintsList, err = clsrules.Apply(eprintsList)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err)
+			fmt.Fprintf(app.Eout, "%s\n", err)
 			os.Exit(1)
 		}
 	}
+	var src []byte
 	if asJSON {
-		src, err := json.MarshalIndent(eprintsList, "", "   ")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err)
-			os.Exit(1)
-		}
-		fmt.Fprintf(os.Stdout, "%s\n", src)
-		os.Exit(0)
+		src, err = json.MarshalIndent(eprintsList, "", "   ")
+	} else {
+		src, err = xml.MarshalIndent(eprintsList, "", "   ")
 	}
-	src, err := xml.MarshalIndent(eprintsList, "", "   ")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+		fmt.Fprintf(app.Eout, "%s\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(app.Out, "%s\n", src)
+	// NOTE: Only fail if none of the DOI could be converted
+	if errCnt > 0 && errCnt == len(args) {
+		cli.CloseFile(outputFName, app.Out)
 		os.Exit(1)
 	}
-	fmt.Fprintf(os.Stdout, "%s\n", src)
 }
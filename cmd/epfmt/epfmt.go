@@ -23,8 +23,12 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	// Caltech Library Packages
 	"github.com/caltechlibrary/cli"
@@ -72,11 +76,25 @@ Render EPrint JSON as EPrint XML.
     epfmt -xml < 123.json
 ` + "```" + `
 
+The input and output filenames can also be given as
+parameters.
+
+` + "```" + `
+    epfmt -json 123.xml 123.json
+` + "```" + `
+
 _epfmt_ will first parse the XML or JSON 
 presented to it and pretty print the output 
 in the desired format requested. If no 
 format option chosen it will pretty print 
 in the same format as input.
+
+Report EPrint XML elements that would be dropped 
+because eprinttools doesn't map them.
+
+` + "```" + `
+    epfmt -strict -json < 123.xml
+` + "```" + `
 `)
 
 	// Standard Options
@@ -95,8 +113,39 @@ in the same format as input.
 	// App Options
 	asJSON bool
 	asXML  bool
+	strict bool
 )
 
+// reportUnmapped writes the unmapped element names for each eprint
+// followed by a count summary of records per element name.
+func reportUnmapped(out io.Writer, src []byte) {
+	unmapped, err := eprinttools.UnmappedFields(src)
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", err)
+		return
+	}
+	ids := []string{}
+	for id := range unmapped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	summary := map[string]int{}
+	for _, id := range ids {
+		fmt.Fprintf(out, "eprint %s unmapped: %s\n", id, strings.Join(unmapped[id], ", "))
+		for _, name := range unmapped[id] {
+			summary[name]++
+		}
+	}
+	names := []string{}
+	for name := range summary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%s: %d record(s)\n", name, summary[name])
+	}
+}
+
 func main() {
 	var (
 		inputFmt int
@@ -106,6 +155,7 @@ func main() {
 	)
 
 	app := cli.NewCli(eprinttools.Version)
+	app.SetParams("[INPUT_FILENAME]", "[OUTPUT_FILENAME]")
 
 	// Add Help Docs
 	app.AddHelp("synopsis", synopsis)
@@ -117,7 +167,7 @@ func main() {
 	app.BoolVar(&showLicense, "l,license", false, "display license")
 	app.BoolVar(&showVersion, "v,version", false, "display version")
 	app.BoolVar(&showExamples, "e,examples", false, "display examples")
-	app.StringVar(&inputFName, "i,input", "", "input file name (read the URL connection string from the input file")
+	app.StringVar(&inputFName, "i,input", "", "input file name")
 	app.StringVar(&outputFName, "o,output", "", "output file name")
 	app.BoolVar(&quiet, "quiet", false, "suppress error messages")
 	app.BoolVar(&newLine, "nl,newline", false, "if true add a trailing newline")
@@ -127,16 +177,17 @@ func main() {
 	// App Options
 	app.BoolVar(&asXML, "xml", false, "output EPrint XML")
 	app.BoolVar(&asJSON, "json", false, "output JSON version of EPrint XML")
+	app.BoolVar(&strict, "strict", false, "report EPrint XML elements not mapped by eprinttools to standard error")
 
 	// We're ready to process args
 	app.Parse()
 	args := app.Args()
 
-	if len(args) > 1 {
-		inputFName = args[1]
+	if len(args) > 0 {
+		inputFName = args[0]
 	}
-	if len(args) > 2 {
-		outputFName = args[2]
+	if len(args) > 1 {
+		outputFName = args[1]
 	}
 
 	// Setup IO
@@ -194,18 +245,30 @@ func main() {
 		// Unmarshal as JSON
 		inputFmt = IsJSON
 		err = json.Unmarshal(src, &obj)
+		if err == nil && len(obj.EPrint) == 0 {
+			// NOTE: A single EPrint object (e.g. a line of eprints2jsonl)
+			e := new(eprinttools.EPrint)
+			if err = json.Unmarshal(src, &e); err == nil {
+				obj.EPrint = append(obj.EPrint, e)
+			}
+		}
 	} else {
 		// Unmarshal as EPrintXML
 		inputFmt = IsXML
-		err = xml.Unmarshal(src, &obj)
+		err = eprinttools.DecodeXML(src, &obj)
+		if err == nil && strict {
+			reportUnmapped(app.Eout, src)
+		}
 	}
 	if err != nil {
 		fmt.Fprintf(app.Eout, "%s\n", err)
 		os.Exit(1)
 	}
 
+	now := time.Now()
 	for _, e := range obj.EPrint {
 		e.SyntheticFields()
+		e.SetAccessRights(now)
 	}
 
 	// marshal pretty printed output based on options selected.
@@ -0,0 +1,388 @@
+//
+// eprints2jsonl.go - streams EPrint records as JSON Lines
+//
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	// Caltech Library packages
+	"github.com/caltechlibrary/cli"
+	"github.com/caltechlibrary/eprinttools"
+)
+
+var (
+	synopsis = `_eprints2jsonl_ streams EPrint records as JSON Lines`
+
+	description = `_eprints2jsonl_ writes each EPrint record as one
+JSON object per line (JSON Lines) so a whole collection can be
+loaded into tools like Spark or pandas without thousands of small
+files or one giant JSON array. If the parameter is an EPrints
+URL the records are harvested from the EPrint 3.x REST API,
+otherwise the parameters are treated as EPrint XML files. If no
+parameter is provided then standard input is read as EPrint XML.
+Records are written as they are retrieved so the order follows
+completion, not eprint id. With the -csv option the core fields
+are written as CSV instead and -schema writes a Table Schema
+describing the CSV columns and their types. With the
+-funder-registry option funders are given their Crossref Funder
+Registry DOI (funder_doi), agencies are looked up with the
+CrossRef API and the results kept in the registry file. The
+-ror-registry option works the same way adding the ROR identifier
+(ror) of funder agencies and local groups from the ROR API.
+The eprint ids are read from the REST API's index, following its
+next page links, or with -id-search from an EPrints search
+exported with the Ids plugin.
+`
+
+	examples = `Harvest every record from an EPrints repository
+into a JSON Lines file.
+
+` + "```" + `
+    eprints2jsonl -o authors.jsonl https://example.org
+` + "```" + `
+
+Harvest with a username and secret kept in a credentials
+file only you can read.
+
+` + "```" + `
+    eprints2jsonl -credentials $HOME/.eprint-credentials.json \
+        -o authors.jsonl https://example.org
+` + "```" + `
+
+Harvest the core fields as CSV for an analytics warehouse
+along with a schema file describing the columns.
+
+` + "```" + `
+    eprints2jsonl -csv -schema authors-schema.json \
+        -o authors.csv https://example.org
+` + "```" + `
+
+Harvest adding Crossref Funder Registry DOI to the funders,
+funders.json caches the agency names already looked up.
+
+` + "```" + `
+    eprints2jsonl -funder-registry funders.json \
+        -o authors.jsonl https://example.org
+` + "```" + `
+
+Harvest adding ROR identifiers to the funders and local
+groups, ror.json caches the names already looked up.
+
+` + "```" + `
+    eprints2jsonl -ror-registry ror.json \
+        -o authors.jsonl https://example.org
+` + "```" + `
+
+Harvest the records the repository's search lists instead of
+reading the REST API's index.
+
+` + "```" + `
+    eprints2jsonl -id-search \
+        '/cgi/search/archive/advanced/export_Ids.txt?screen=Search&dataset=archive&_action_export=1&output=Ids&eprintid=1-&satisfyall=ALL&order=eprintid' \
+        -o authors.jsonl https://example.org
+` + "```" + `
+
+Convert EPrint XML dumps to JSON Lines.
+
+` + "```" + `
+    eprints2jsonl eprints-dump-1.xml eprints-dump-2.xml >authors.jsonl
+` + "```" + `
+
+`
+
+	// Standard Options
+	showHelp         bool
+	showLicense      bool
+	showVersion      bool
+	showExamples     bool
+	quiet            bool
+	generateMarkdown bool
+	generateManPage  bool
+	outputFName      string
+
+	// App Options
+	credentials  string
+	workers      int
+	status       string
+	asCSV        bool
+	schemaFName  string
+	fundersFName string
+	rorFName     string
+	idSearch     string
+
+	// csvOut is used to write records when asCSV is true
+	csvOut *eprinttools.CSVWriter
+
+	// funders is used to add funder DOI when fundersFName is set
+	funders *eprinttools.FunderRegistry
+
+	// rors is used to add ROR identifiers when rorFName is set
+	rors *eprinttools.RORRegistry
+
+	// now is when the run started, access rights are as of now
+	now time.Time
+)
+
+// exitOnError flushes the CSV output and saves the registries
+// before exiting if err is not nil
+func exitOnError(eout *os.File, err error) {
+	if err != nil {
+		finish(eout)
+		cli.ExitOnError(eout, err, quiet)
+	}
+}
+
+// finish flushes the CSV output and saves the registries
+func finish(eout io.Writer) {
+	if csvOut != nil {
+		if err := csvOut.Flush(); err != nil && quiet == false {
+			fmt.Fprintf(eout, "%s\n", err)
+		}
+	}
+	saveRegistries(eout)
+}
+
+// saveRegistries saves the funder and ROR registries so lookups are kept
+func saveRegistries(eout io.Writer) {
+	if funders != nil {
+		if err := funders.Save(fundersFName); err != nil && quiet == false {
+			fmt.Fprintf(eout, "%s, %s\n", fundersFName, err)
+		}
+	}
+	if rors != nil {
+		if err := rors.Save(rorFName); err != nil && quiet == false {
+			fmt.Fprintf(eout, "%s, %s\n", rorFName, err)
+		}
+	}
+}
+
+// writeEPrint writes e as a single line of JSON or a CSV row
+func writeEPrint(out io.Writer, eout io.Writer, e *eprinttools.EPrint) error {
+	if funders != nil {
+		//NOTE: a failed lookup leaves the funder DOI out, the record
+		// is still written
+		if err := funders.Apply(e); err != nil && quiet == false {
+			fmt.Fprintf(eout, "eprint %d, %s\n", e.EPrintID, err)
+		}
+	}
+	//NOTE: a failed lookup leaves the item without a ROR, it
+	// shouldn't stop the harvest.
+	if rors != nil {
+		if err := rors.Apply(e); err != nil && quiet == false {
+			fmt.Fprintf(eout, "eprint %d, %s\n", e.EPrintID, err)
+		}
+	}
+	e.SetAccessRights(now)
+	if csvOut != nil {
+		return csvOut.Write(e)
+	}
+	//NOTE: populate the synthetic fields
+	e.SyntheticFields()
+	src, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", src)
+	return err
+}
+
+// harvest retrieves the records from the EPrints REST API at
+// eprintURL writing them to out, returns the count of errors.
+func harvest(out io.Writer, eout io.Writer, eprintURL string) (int, error) {
+	var (
+		authMethod, username, secret string
+	)
+	if credentials != "" {
+		m, err := eprinttools.LoadCredentials(credentials)
+		if err != nil {
+			return 0, err
+		}
+		username, secret = m["EPRINT_USERNAME"], m["EPRINT_PASSWORD"]
+		authMethod = "basic"
+	}
+	api, err := eprinttools.New(eprintURL, false, authMethod, username, secret)
+	if err != nil {
+		return 0, err
+	}
+	if status != "" {
+		api.IncludeStatus = strings.Split(status, ",")
+	}
+	api.IDSearch = idSearch
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	//NOTE: ids are fed to the batch as they are read from the list
+	uris := make(chan string)
+	var walkErr error
+	go func() {
+		defer close(uris)
+		walkErr = api.WalkEPrintsURI(func(uri string) error {
+			select {
+			case uris <- uri:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	errCnt := 0
+	for result := range api.StreamEPrintsBatch(ctx, uris, workers) {
+		if result.Err != nil && result.EPrint != nil {
+			//NOTE: records not in IncludeStatus are skipped
+			continue
+		}
+		if result.Err != nil {
+			if quiet == false {
+				fmt.Fprintf(eout, "%s, %s\n", result.URI, result.Err)
+			}
+			errCnt++
+			continue
+		}
+		if err := writeEPrint(out, eout, result.EPrint); err != nil {
+			return errCnt, err
+		}
+	}
+	return errCnt, walkErr
+}
+
+// convert reads EPrint XML from in writing the records to out
+func convert(out io.Writer, eout io.Writer, in io.Reader) error {
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	data := new(eprinttools.EPrints)
+	if err := eprinttools.DecodeXML(src, &data); err != nil {
+		return err
+	}
+	for _, e := range data.EPrint {
+		if err := writeEPrint(out, eout, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	var (
+		err error
+	)
+	app := cli.NewCli(eprinttools.Version)
+
+	app.SetParams("[EPRINT_URL|EPRINT_XML_FILES]")
+
+	// Add Help
+	app.AddHelp("synopsis", []byte(synopsis))
+	app.AddHelp("description", []byte(description))
+	app.AddHelp("examples", []byte(examples))
+
+	// Standard Options
+	app.BoolVar(&showHelp, "h,help", false, "display help")
+	app.BoolVar(&showLicense, "l,license", false, "display license")
+	app.BoolVar(&showVersion, "v,version", false, "display version")
+	app.BoolVar(&showExamples, "e,examples", false, "display examples")
+	app.StringVar(&outputFName, "o,output", "", "output file name")
+	app.BoolVar(&quiet, "quiet", false, "suppress error messages")
+	app.BoolVar(&generateMarkdown, "generate-markdown", false, "generate Markdown documentation")
+	app.BoolVar(&generateManPage, "generate-manpage", false, "generate man page")
+
+	// App Options
+	app.StringVar(&credentials, "credentials", "", "read EPRINT_USERNAME and EPRINT_PASSWORD from a JSON file (must be chmod 600)")
+	app.IntVar(&workers, "workers", 4, "number of records to retrieve concurrently")
+	app.StringVar(&status, "status", "", "comma separated eprint_status values to include (default archive)")
+	app.BoolVar(&asCSV, "csv", false, "write the core fields as CSV instead of JSON Lines")
+	app.StringVar(&schemaFName, "schema", "", "write a Table Schema JSON file describing the CSV columns")
+	app.StringVar(&fundersFName, "funder-registry", "", "add Crossref Funder Registry DOI to funders, caching lookups in this JSON file")
+	app.StringVar(&idSearch, "id-search", "", "list eprint ids with this EPrints search (Ids export) instead of the REST API index")
+	app.StringVar(&rorFName, "ror-registry", "", "add ROR identifiers to funders and local groups, caching lookups in this JSON file")
+
+	// We're ready to process args
+	app.Parse()
+	args := app.Args()
+
+	// Setup IO
+	app.Eout = os.Stderr
+	now = time.Now()
+
+	app.Out, err = cli.Create(outputFName, os.Stdout)
+	cli.ExitOnError(app.Eout, err, quiet)
+	defer cli.CloseFile(outputFName, app.Out)
+
+	// Handle options
+	if generateMarkdown {
+		app.GenerateMarkdown(app.Out)
+		os.Exit(0)
+	}
+	if generateManPage {
+		app.GenerateManPage(app.Out)
+		os.Exit(0)
+	}
+	if showHelp || showExamples {
+		if len(args) > 0 {
+			fmt.Fprintf(app.Out, app.Help(args...))
+		} else {
+			app.Usage(app.Out)
+		}
+		os.Exit(0)
+	}
+	if showLicense {
+		fmt.Fprintln(app.Out, app.License())
+		os.Exit(0)
+	}
+	if showVersion {
+		fmt.Fprintln(app.Out, app.Version())
+		os.Exit(0)
+	}
+
+	if schemaFName != "" {
+		out, err := os.Create(schemaFName)
+		cli.ExitOnError(app.Eout, err, quiet)
+		err = eprinttools.WriteCSVSchema(out)
+		out.Close()
+		cli.ExitOnError(app.Eout, err, quiet)
+	}
+	if fundersFName != "" {
+		funders, err = eprinttools.LoadFunderRegistry(fundersFName)
+		cli.ExitOnError(app.Eout, err, quiet)
+	}
+	if rorFName != "" {
+		rors, err = eprinttools.LoadRORRegistry(rorFName)
+		cli.ExitOnError(app.Eout, err, quiet)
+	}
+	if asCSV {
+		csvOut = eprinttools.NewCSVWriter(app.Out)
+	}
+
+	//NOTE: from here on exit with exitOnError so the CSV output
+	// and registries are not lost
+	switch {
+	case len(args) == 0:
+		err = convert(app.Out, app.Eout, os.Stdin)
+		exitOnError(app.Eout, err)
+	case strings.HasPrefix(args[0], "http://") || strings.HasPrefix(args[0], "https://"):
+		errCnt, err := harvest(app.Out, app.Eout, args[0])
+		exitOnError(app.Eout, err)
+		if errCnt > 0 {
+			finish(app.Eout)
+			os.Exit(1)
+		}
+	default:
+		for _, fName := range args {
+			in, err := os.Open(fName)
+			exitOnError(app.Eout, err)
+			err = convert(app.Out, app.Eout, in)
+			in.Close()
+			if err != nil {
+				exitOnError(app.Eout, fmt.Errorf("%s, %s", fName, err))
+			}
+		}
+	}
+	finish(app.Eout)
+}
@@ -5,10 +5,12 @@ package main
 
 import (
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"time"
 
 	// Caltech Library packages
 	"github.com/caltechlibrary/cli"
@@ -25,6 +27,16 @@ to read the EPrint XML. If the EPrint XML isn't understood
 then an error message will be written and an exit code of 1
 used to close the process otherwise the process will render
 JSON to standard out.
+
+With the -output-dir option the EPrint XML is streamed, each
+record is written as its own JSON document named for its eprint
+id (e.g. 1234.json) as soon as it is decoded. This lets you
+convert an admin export of the whole repository without holding
+it in memory. Records without an eprint id are skipped with
+an error message and the exit code is 1. The -custom-fields
+option works the same way with -output-dir. The -file-base-url
+option rewrites the document file URLs to be served from
+another location.
 `
 
 	examples = `Converting a document, eprints-dump.xml, to JSON.
@@ -39,6 +51,23 @@ Or
     cat eprints-dump.xml | eprintxml2json 
 ` + "```" + `
 
+Including site specific fields, listed in custom-fields.json
+as field names and element paths (e.g. 
+{"option_minor": "option_minor/item"}), in the JSON output.
+
+` + "```" + `
+    eprintxml2json -custom-fields custom-fields.json eprints-dump.xml
+` + "```" + `
+
+Converting an admin export to a directory of JSON documents,
+one per record, with the file URLs pointing at a file server.
+
+` + "```" + `
+    eprintxml2json -output-dir authors-json \
+        -file-base-url https://files.example.edu/authors \
+        authors-export.xml
+` + "```" + `
+
 `
 
 	// Standard Options
@@ -55,8 +84,63 @@ Or
 	inputFName       string
 	outputFName      string
 	prettyPrint      bool
+
+	// App Options
+	customFieldsFName string
+	outputDir         string
+	fileBaseURL       string
+
+	// now is when the run started, access rights are as of now
+	now time.Time
 )
 
+// marshalEPrints renders v as JSON honoring the -p option
+func marshalEPrints(v interface{}) ([]byte, error) {
+	if prettyPrint {
+		return json.MarshalIndent(v, "", "    ")
+	}
+	return json.Marshal(v)
+}
+
+// streamToDir decodes the EPrint XML from in writing each record to
+// outputDir as <eprint_id>.json, custom fields are included when
+// selectors is not empty. Records without an eprint id are reported to
+// eout and skipped. Returns the count of records written and skipped.
+func streamToDir(in io.Reader, eout io.Writer, selectors map[string]string) (int, int, error) {
+	if err := os.MkdirAll(outputDir, 0775); err != nil {
+		return 0, 0, err
+	}
+	cnt, skipped := 0, 0
+	err := eprinttools.DecodeXMLStreamCustomFields(in, selectors, func(e *eprinttools.EPrint) error {
+		if e.EPrintID == 0 {
+			skipped++
+			if quiet == false {
+				fmt.Fprintf(eout, "record %d has no eprint id, skipped\n", cnt+skipped)
+			}
+			return nil
+		}
+		//NOTE: populate the synthetic fields
+		e.SyntheticFields()
+		e.SetAccessRights(now)
+		if fileBaseURL != "" {
+			if err := e.RewriteFileURLs(fileBaseURL); err != nil {
+				return fmt.Errorf("eprint %d, %s", e.EPrintID, err)
+			}
+		}
+		src, err := marshalEPrints(e)
+		if err != nil {
+			return fmt.Errorf("eprint %d, %s", e.EPrintID, err)
+		}
+		fName := path.Join(outputDir, fmt.Sprintf("%d.json", e.EPrintID))
+		if err := ioutil.WriteFile(fName, src, 0664); err != nil {
+			return err
+		}
+		cnt++
+		return nil
+	})
+	return cnt, skipped, err
+}
+
 func main() {
 	var (
 		err error
@@ -83,6 +167,11 @@ func main() {
 	app.BoolVar(&generateManPage, "generate-manpage", false, "generate man page")
 	app.BoolVar(&prettyPrint, "p,pretty", true, "pretty print output")
 
+	// App Options
+	app.StringVar(&customFieldsFName, "custom-fields", "", "read a JSON file of custom field names and element paths to include as custom_fields")
+	app.StringVar(&outputDir, "output-dir", "", "stream the records into this directory as one JSON document per eprint id")
+	app.StringVar(&fileBaseURL, "file-base-url", "", "rewrite document file URLs to use this base URL")
+
 	// We're ready to process args
 	app.Parse()
 	args := app.Args()
@@ -92,6 +181,7 @@ func main() {
 	}
 	// Setup IO
 	app.Eout = os.Stderr
+	now = time.Now()
 
 	app.In, err = cli.Open(inputFName, os.Stdin)
 	cli.ExitOnError(app.Eout, err, quiet)
@@ -127,34 +217,70 @@ func main() {
 		os.Exit(0)
 	}
 
+	if outputDir != "" {
+		var selectors map[string]string
+		if customFieldsFName != "" {
+			selectors, err = eprinttools.LoadCustomFieldSelectors(customFieldsFName)
+			if err != nil {
+				fmt.Fprintf(app.Eout, "%s\n", err)
+				os.Exit(1)
+			}
+		}
+		cnt, skipped, err := streamToDir(app.In, app.Eout, selectors)
+		if err != nil {
+			fmt.Fprintf(app.Eout, "%s\n", err)
+			os.Exit(1)
+		}
+		if quiet == false {
+			fmt.Fprintf(app.Eout, "%d records written to %s\n", cnt, outputDir)
+		}
+		if skipped > 0 {
+			if quiet == false {
+				fmt.Fprintf(app.Eout, "%d records skipped, no eprint id\n", skipped)
+			}
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	src, err := ioutil.ReadAll(app.In)
 	if err != nil {
 		fmt.Fprintf(app.Eout, "%s\n", err)
 		os.Exit(1)
 	}
 	data := new(eprinttools.EPrints)
-	err = xml.Unmarshal(src, &data)
+	err = eprinttools.DecodeXML(src, &data)
 	if err != nil {
 		fmt.Fprintf(app.Eout, "%s\n", err)
 		os.Exit(1)
 	}
-	//NOTE: populate the synthetic fields
-	for _, e := range data.EPrint {
-		e.SyntheticFields()
-	}
-	if prettyPrint {
-		src, err = json.MarshalIndent(data, "", "    ")
+	if customFieldsFName != "" {
+		selectors, err := eprinttools.LoadCustomFieldSelectors(customFieldsFName)
 		if err != nil {
 			fmt.Fprintf(app.Eout, "%s\n", err)
 			os.Exit(1)
 		}
-	} else {
-		src, err = json.Marshal(data)
-		if err != nil {
+		if err := data.ApplyCustomFields(src, selectors); err != nil {
 			fmt.Fprintf(app.Eout, "%s\n", err)
 			os.Exit(1)
 		}
 	}
+	//NOTE: populate the synthetic fields
+	for _, e := range data.EPrint {
+		e.SyntheticFields()
+		e.SetAccessRights(now)
+		if fileBaseURL != "" {
+			if err := e.RewriteFileURLs(fileBaseURL); err != nil {
+				fmt.Fprintf(app.Eout, "eprint %d, %s\n", e.EPrintID, err)
+				os.Exit(1)
+			}
+		}
+	}
+	src, err = marshalEPrints(data)
+	if err != nil {
+		fmt.Fprintf(app.Eout, "%s\n", err)
+		os.Exit(1)
+	}
 	if newLine == true {
 		fmt.Fprintf(app.Out, "%s\n", src)
 	} else {
@@ -0,0 +1,33 @@
+package eprinttools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+)
+
+// LoadCredentials reads secrets from a JSON file keyed by the same
+// names as the environment variables they replace (e.g.
+// {"EPRINT_USERNAME": "user", "EPRINT_PASSWORD": "secret"}). This keeps
+// passwords out of process listings and shell history. The file must
+// not be readable by group or others (e.g. chmod 600).
+func LoadCredentials(fName string) (map[string]string, error) {
+	info, err := os.Stat(fName)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && (info.Mode().Perm()&0077) != 0 {
+		return nil, fmt.Errorf("%s permissions are %s, must not be accessible by group or others", fName, info.Mode().Perm())
+	}
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return nil, err
+	}
+	credentials := map[string]string{}
+	if err := json.Unmarshal(src, &credentials); err != nil {
+		return nil, fmt.Errorf("can't read credentials from %s, %s", fName, err)
+	}
+	return credentials, nil
+}
@@ -0,0 +1,37 @@
+package eprinttools
+
+import (
+	"io/ioutil"
+	"path"
+	"runtime"
+	"testing"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	fName := path.Join(t.TempDir(), "credentials.json")
+	src := []byte(`{"EPRINT_USERNAME": "user", "EPRINT_PASSWORD": "secret"}`)
+	if err := ioutil.WriteFile(fName, src, 0600); err != nil {
+		t.Errorf("can't write %s, %s", fName, err)
+		t.FailNow()
+	}
+	credentials, err := LoadCredentials(fName)
+	if err != nil {
+		t.Errorf("LoadCredentials() returned an error, %s", err)
+		t.FailNow()
+	}
+	if credentials["EPRINT_USERNAME"] != "user" || credentials["EPRINT_PASSWORD"] != "secret" {
+		t.Errorf("unexpected credentials %+v", credentials)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	fName = path.Join(t.TempDir(), "readable.json")
+	if err := ioutil.WriteFile(fName, src, 0644); err != nil {
+		t.Errorf("can't write %s, %s", fName, err)
+		t.FailNow()
+	}
+	if _, err := LoadCredentials(fName); err == nil {
+		t.Errorf("expected an error for a group/world readable credentials file")
+	}
+}
@@ -3,36 +3,84 @@ package eprinttools
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	// Caltech Library Packages
 	"github.com/caltechlibrary/crossrefapi"
 )
 
-// normalizeCrossRefType converts content type from CrossRef
-// to Authors (e.g. "journal-article" to "article")
-func normalizeCrossRefType(s string) string {
-	switch strings.ToLower(s) {
-	case "proceedings-article":
-		//NOTE: This seems vary idiosyncratic to CaltechAUTHORS
-		return "book_section"
-	case "journal-article":
-		return "article"
-	case "book-chapter":
-		return "book_section"
-	default:
-		return s
+// crossRefDateParts converts a CrossRef "date-parts" value
+// (e.g. [[2019, 6, 1]]) into a date string (e.g. 2019-06-01).
+func crossRefDateParts(dateParts interface{}) string {
+	l1, ok := dateParts.([]interface{})
+	if ok == false || len(l1) != 1 {
+		return ""
 	}
+	l2, ok := l1[0].([]interface{})
+	if ok == false {
+		return ""
+	}
+	ymd := []string{}
+	for _, v := range l2 {
+		//NOTE: date parts can be null, the date ends there
+		num, ok := v.(json.Number)
+		if ok == false {
+			break
+		}
+		n := num.String()
+		if len(n) < 2 {
+			n = "0" + n
+		}
+		ymd = append(ymd, n)
+	}
+	return strings.Join(ymd, "-")
+}
+
+// crossRefPersonToItem converts a CrossRef contributor object (e.g. an
+// author or editor) into an Item with a Name. Organizations (objects
+// with a "name" rather than "given" and "family") populate Name.Value.
+func crossRefPersonToItem(person map[string]interface{}) *Item {
+	item := new(Item)
+	item.Name = new(Name)
+	if orcid, ok := person["ORCID"]; ok == true {
+		//NOTE: skip ORCID that fail checksum validation
+		if s, err := NormalizeORCID(orcid.(string)); err == nil {
+			item.ORCID = s
+		}
+	}
+	if family, ok := person["family"]; ok == true {
+		item.Name.Family = family.(string)
+	}
+	if given, ok := person["given"]; ok == true {
+		item.Name.Given = given.(string)
+	}
+	//NOTE: if as have a 'name' then we'll add it to
+	// as a corp_creators
+	if name, ok := person["name"]; ok == true {
+		item.Name.Value = strings.TrimSpace(name.(string))
+		if strings.HasPrefix(item.Name.Value, "(") && strings.HasSuffix(item.Name.Value, ")") {
+			item.Name.Value = strings.TrimSuffix(strings.TrimPrefix(item.Name.Value, "("), ")")
+		}
+	}
+	return item
 }
 
 // CrossRefWorksToEPrint takes a works object from the CrossRef API
 // and maps the fields into an EPrint struct return a new struct or
-// error.
+// error. It uses the DefaultCrossRefRules.
 func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
+	return DefaultCrossRefRules().WorksToEPrint(obj)
+}
+
+// WorksToEPrint takes a works object from the CrossRef API and maps
+// the fields into an EPrint struct using rules, returns a new struct
+// or error.
+func (rules *CrossRefRules) WorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 	eprint := new(EPrint)
 	// Type
 	if s, ok := indexInto(obj, "message", "type"); ok == true {
-		eprint.Type = normalizeCrossRefType(fmt.Sprintf("%s", s))
+		eprint.Type = rules.eprintType(fmt.Sprintf("%s", s))
 	} else {
 		return nil, fmt.Errorf("Can't find type in object")
 	}
@@ -97,16 +145,38 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 
 	}
 
-	// ISBN
-	if a, ok := indexInto(obj, "message", "ISBN"); ok == true {
+	// ISBN, prefer the print ISBN when CrossRef tells us the type
+	if a, ok := indexInto(obj, "message", "isbn-type"); ok == true {
+		for _, o := range a.([]interface{}) {
+			m := o.(map[string]interface{})
+			if t, ok := indexInto(m, "type"); ok == true && t == "print" {
+				if s, ok := indexInto(m, "value"); ok == true {
+					eprint.ISBN = fmt.Sprintf("%s", s)
+					break
+				}
+			}
+		}
+	}
+	if a, ok := indexInto(obj, "message", "ISBN"); ok == true && eprint.ISBN == "" {
 		if len(a.([]interface{})) > 0 {
 			s := a.([]interface{})[0]
 			eprint.ISBN = fmt.Sprintf("%s", s)
 		}
 	}
 
-	// ISSN
-	if a, ok := indexInto(obj, "message", "ISSN"); ok == true {
+	// ISSN, prefer the ISSN type given in rules
+	if a, ok := indexInto(obj, "message", "issn-type"); ok == true && rules.ISSNType != "" {
+		for _, o := range a.([]interface{}) {
+			m := o.(map[string]interface{})
+			if t, ok := indexInto(m, "type"); ok == true && t == rules.ISSNType {
+				if s, ok := indexInto(m, "value"); ok == true {
+					eprint.ISSN = fmt.Sprintf("%s", s)
+					break
+				}
+			}
+		}
+	}
+	if a, ok := indexInto(obj, "message", "ISSN"); ok == true && eprint.ISSN == "" {
 		if len(a.([]interface{})) > 0 {
 			eprint.ISSN = fmt.Sprintf("%s", a.([]interface{})[0])
 		}
@@ -117,6 +187,15 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 	if eprint.Title != "" && eprint.Type == "book" {
 		eprint.BookTitle = eprint.Title
 	}
+	// Book sections (chapters, proceedings articles) take their book
+	// title from the container title
+	if eprint.Type == "book_section" {
+		if l, ok := indexInto(obj, "message", "container-title"); ok == true {
+			if len(l.([]interface{})) > 0 {
+				eprint.BookTitle = l.([]interface{})[0].(string)
+			}
+		}
+	}
 
 	// Funders
 	if a, ok := indexInto(obj, "message", "funder"); ok == true {
@@ -127,6 +206,9 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 			if name, ok := indexInto(m, "name"); ok == true && name != "N/A" {
 				entry.Agency = fmt.Sprintf("%s", name)
 			}
+			if doi, ok := indexInto(m, "DOI"); ok == true {
+				entry.FunderDOI = NormalizeDOI(fmt.Sprintf("%s", doi))
+			}
 			if a2, ok := indexInto(m, "award"); ok == true && a2 != "N/A" {
 				if len(a2.([]interface{})) > 0 {
 					entry.GrantNumber = fmt.Sprintf("%s", a2.([]interface{})[0])
@@ -138,6 +220,65 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 		}
 	}
 
+	// Reports are EPrints monographs, their issuing institution is
+	// listed separately from the publisher
+	if s, ok := indexInto(obj, "message", "type"); ok == true {
+		eprint.MonographType = rules.MonographTypes[strings.ToLower(fmt.Sprintf("%s", s))]
+	}
+	if a, ok := indexInto(obj, "message", "institution"); ok == true {
+		var l []interface{}
+		switch a.(type) {
+		case []interface{}:
+			l = a.([]interface{})
+		case map[string]interface{}:
+			l = append(l, a)
+		}
+		if len(l) > 0 {
+			if m, ok := l[0].(map[string]interface{}); ok == true {
+				if s, ok := indexInto(m, "name"); ok == true {
+					eprint.Institution = fmt.Sprintf("%s", s)
+				}
+				if a2, ok := indexInto(m, "department"); ok == true {
+					if l2, ok := a2.([]interface{}); ok == true && len(l2) > 0 {
+						eprint.Department = fmt.Sprintf("%s", l2[0])
+					}
+				}
+				if a2, ok := indexInto(m, "place"); ok == true && eprint.PlaceOfPub == "" {
+					if l2, ok := a2.([]interface{}); ok == true && len(l2) > 0 {
+						eprint.PlaceOfPub = fmt.Sprintf("%s", l2[0])
+					}
+				}
+			}
+		}
+	}
+
+	// Event (e.g. conference) the work was presented at
+	event, _ := indexInto(obj, "message", "event")
+	if m, ok := event.(map[string]interface{}); ok == true {
+		if s, ok := indexInto(m, "name"); ok == true {
+			eprint.EventTitle = strings.TrimSpace(fmt.Sprintf("%s", s))
+		}
+		if s, ok := indexInto(m, "location"); ok == true {
+			eprint.EventLocation = strings.TrimSpace(fmt.Sprintf("%s", s))
+		}
+		start, end := "", ""
+		if dp, ok := indexInto(m, "start", "date-parts"); ok == true {
+			start = crossRefDateParts(dp)
+		}
+		if dp, ok := indexInto(m, "end", "date-parts"); ok == true {
+			end = crossRefDateParts(dp)
+		}
+		switch {
+		case start != "" && end != "" && start != end:
+			eprint.EventDates = fmt.Sprintf("%s - %s", start, end)
+		case start != "":
+			eprint.EventDates = start
+		}
+		if eprint.EventTitle != "" {
+			eprint.EventType = "conference"
+		}
+	}
+
 	// NOTE: Caltech Library puts the DOI in the related URL field rather than
 	// in EPrint's default location. This code puts the DOI in the default
 	// location. If you need Caltech Library's bahavior use clsrules.Apply()
@@ -193,20 +334,7 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 	// fallback to issued date then finally created date.
 	eprint.DateType = "published"
 	if published, ok := indexInto(obj, "message", "published-print", "date-parts"); ok == true {
-		var l1, l2 []interface{}
-		if len(published.([]interface{})) == 1 {
-			l1 = published.([]interface{})
-			l2 = l1[0].([]interface{})
-			ymd := []string{}
-			for _, v := range l2 {
-				n := v.(json.Number).String()
-				if len(n) < 2 {
-					n = "0" + n
-				}
-				ymd = append(ymd, n)
-			}
-			eprint.Date = strings.Join(ymd, "-")
-		}
+		eprint.Date = crossRefDateParts(published)
 	} else if issued, ok := indexInto(obj, "message", "issued", "date-time"); ok == true {
 		// DateType
 		eprint.Date = fmt.Sprintf("%s", issued)
@@ -223,32 +351,7 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 		creators := new(CreatorItemList)
 		corpCreators := new(CorpCreatorItemList)
 		for _, entry := range l.([]interface{}) {
-			author := entry.(map[string]interface{})
-			item := new(Item)
-			item.Name = new(Name)
-			if orcid, ok := author["ORCID"]; ok == true {
-				item.ORCID = orcid.(string)
-				if strings.HasPrefix(orcid.(string), "http://orcid.org/") {
-					item.ORCID = strings.TrimPrefix(orcid.(string), "http://orcid.org/")
-				}
-				if strings.HasPrefix(orcid.(string), "https://orcid.org/") {
-					item.ORCID = strings.TrimPrefix(orcid.(string), "http://orcid.org/")
-				}
-			}
-			if family, ok := author["family"]; ok == true {
-				item.Name.Family = family.(string)
-			}
-			if given, ok := author["given"]; ok == true {
-				item.Name.Given = given.(string)
-			}
-			//NOTE: if as have a 'name' then we'll add it to
-			// as a corp_creators
-			if name, ok := author["name"]; ok == true {
-				item.Name.Value = strings.TrimSpace(name.(string))
-				if strings.HasPrefix(item.Name.Value, "(") && strings.HasSuffix(item.Name.Value, ")") {
-					item.Name.Value = strings.TrimSuffix(strings.TrimPrefix(item.Name.Value, "("), ")")
-				}
-			}
+			item := crossRefPersonToItem(entry.(map[string]interface{}))
 			if item.Name.Given != "" && item.Name.Family != "" {
 				creators.AddItem(item)
 			}
@@ -264,6 +367,43 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 		}
 	}
 
+	// Editors
+	if l, ok := indexInto(obj, "message", "editor"); ok == true {
+		editors := new(EditorItemList)
+		for _, entry := range l.([]interface{}) {
+			item := crossRefPersonToItem(entry.(map[string]interface{}))
+			if (item.Name.Given != "" && item.Name.Family != "") || item.Name.Value != "" {
+				editors.AddItem(item)
+			}
+		}
+		if len(editors.Items) > 0 {
+			eprint.Editors = editors
+		}
+	}
+
+	// Contributors, CrossRef lists these by role. The roles are
+	// sorted so the contributors are always in the same order.
+	roles := []string{}
+	for role := range rules.ContributorRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		relator := rules.ContributorRoles[role]
+		if l, ok := indexInto(obj, "message", role); ok == true {
+			for _, entry := range l.([]interface{}) {
+				item := crossRefPersonToItem(entry.(map[string]interface{}))
+				if (item.Name.Given != "" && item.Name.Family != "") || item.Name.Value != "" {
+					item.Type = relator
+					if eprint.Contributors == nil {
+						eprint.Contributors = new(ContributorItemList)
+					}
+					eprint.Contributors.AddItem(item)
+				}
+			}
+		}
+	}
+
 	// Edition
 	//FIXME: Need to find value in CrossRef works metadata for this
 
@@ -276,24 +416,21 @@ func CrossRefWorksToEPrint(obj crossrefapi.Object) (*EPrint, error) {
 	// Note
 	//FIXME: Need to find value in CrossRef works metadata for this
 
-	// Abstract
-	//FIXME: Need to find value in CrossRef works metadata for this
+	// Abstract, CrossRef abstracts are JATS markup
+	if s, ok := indexInto(obj, "message", "abstract"); ok == true {
+		if rules.AbstractFormat == "html" {
+			eprint.Abstract = JATSToHTML(fmt.Sprintf("%s", s))
+		} else {
+			eprint.Abstract = JATSToText(fmt.Sprintf("%s", s))
+		}
+	}
 
 	// Refereed
 	//FIXME: Need to find value in CrossRef works metadata for this
 
-	// Editors (*EditorItemList)
-	//FIXME: Need to find value in CrossRef works metadata for this
-
 	// Projects
 	//FIXME: Need to find value in CrossRef works metadata for this
 
-	// Contributors (*ContriborItemList)
-	//FIXME: Need to find value in CrossRef works metadata for this
-
-	// MonographType
-	//FIXME: Need to find value in CrossRef works metadata for this
-
 	// Subjects
 	//FIXME: Need to find value in CrossRef works metadata for this
 
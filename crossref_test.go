@@ -0,0 +1,346 @@
+package eprinttools
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	// Caltech Library Packages
+	"github.com/caltechlibrary/crossrefapi"
+)
+
+// crossRefObject decodes src the way crossrefapi does for a works
+// response.
+func crossRefObject(t *testing.T, src []byte) crossrefapi.Object {
+	obj := crossrefapi.Object{}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		t.Errorf("can't decode test object, %s", err)
+		t.FailNow()
+	}
+	return obj
+}
+
+func TestCrossRefEditorsAndContributors(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "book-chapter",
+    "title": ["A Chapter"],
+    "author": [
+      {"given": "Jane", "family": "Doe", "ORCID": "https://orcid.org/0000-0002-1694-233x"},
+      {"name": "Example Collaboration"}
+    ],
+    "editor": [
+      {"given": "Robert", "family": "Editor", "ORCID": "http://orcid.org/0000-0002-1825-0098"},
+      {"name": "Editorial Board"}
+    ],
+    "translator": [
+      {"given": "Tessa", "family": "Translator"}
+    ]
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Creators == nil || len(eprint.Creators.Items) != 1 {
+		t.Errorf("expected one creator, got %+v", eprint.Creators)
+	}
+	if eprint.Creators != nil && len(eprint.Creators.Items) > 0 && eprint.Creators.Items[0].ORCID != "0000-0002-1694-233X" {
+		t.Errorf("expected normalized ORCID, got %q", eprint.Creators.Items[0].ORCID)
+	}
+	if eprint.CorpCreators == nil || len(eprint.CorpCreators.Items) != 1 {
+		t.Errorf("expected one corp creator, got %+v", eprint.CorpCreators)
+	}
+	if eprint.Editors == nil || len(eprint.Editors.Items) != 2 {
+		t.Errorf("expected two editors, got %+v", eprint.Editors)
+	} else if eprint.Editors.Items[0].Name.Family != "Editor" {
+		t.Errorf("expected family name Editor, got %+v", eprint.Editors.Items[0].Name)
+	}
+	if eprint.Editors != nil && len(eprint.Editors.Items) > 0 && eprint.Editors.Items[0].ORCID != "" {
+		t.Errorf("expected invalid ORCID to be dropped, got %q", eprint.Editors.Items[0].ORCID)
+	}
+	if eprint.Contributors == nil || len(eprint.Contributors.Items) != 1 {
+		t.Errorf("expected one contributor, got %+v", eprint.Contributors)
+		t.FailNow()
+	}
+	if item := eprint.Contributors.Items[0]; item.Type != "http://www.loc.gov/loc.terms/relators/TRL" || item.Name.Family != "Translator" {
+		t.Errorf("expected translator contributor, got %+v", item)
+	}
+}
+
+func TestCrossRefEvent(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "proceedings-article",
+    "title": ["A Paper"],
+    "published-print": {"date-parts": [[2019, 7]]},
+    "event": {
+      "name": "International Conference on Examples",
+      "location": "Pasadena, CA",
+      "start": {"date-parts": [[2019, 6, 3]]},
+      "end": {"date-parts": [[2019, 6, 7]]}
+    }
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Date != "2019-07" {
+		t.Errorf("expected date %q, got %q", "2019-07", eprint.Date)
+	}
+	if eprint.EventTitle != "International Conference on Examples" {
+		t.Errorf("unexpected event title %q", eprint.EventTitle)
+	}
+	if eprint.EventLocation != "Pasadena, CA" {
+		t.Errorf("unexpected event location %q", eprint.EventLocation)
+	}
+	if eprint.EventDates != "2019-06-03 - 2019-06-07" {
+		t.Errorf("unexpected event dates %q", eprint.EventDates)
+	}
+	if eprint.EventType != "conference" {
+		t.Errorf("unexpected event type %q", eprint.EventType)
+	}
+}
+
+func TestCrossRefBookSection(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "book-chapter",
+    "title": ["A Chapter"],
+    "container-title": ["A Book of Chapters"],
+    "publisher": "Example Press",
+    "publisher-location": "Pasadena",
+    "ISBN": ["9780000000002", "9780000000019"],
+    "isbn-type": [
+      {"value": "9780000000002", "type": "electronic"},
+      {"value": "9780000000019", "type": "print"}
+    ]
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Type != "book_section" {
+		t.Errorf("expected type book_section, got %q", eprint.Type)
+	}
+	if eprint.BookTitle != "A Book of Chapters" {
+		t.Errorf("unexpected book title %q", eprint.BookTitle)
+	}
+	if eprint.ISBN != "9780000000019" {
+		t.Errorf("expected print ISBN, got %q", eprint.ISBN)
+	}
+	if eprint.Publisher != "Example Press" || eprint.PlaceOfPub != "Pasadena" {
+		t.Errorf("unexpected publisher %q, place of pub %q", eprint.Publisher, eprint.PlaceOfPub)
+	}
+}
+
+func TestCrossRefReport(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "report",
+    "title": ["A Technical Report"],
+    "institution": [
+      {"name": "California Institute of Technology", "place": ["Pasadena, CA"], "department": ["Computing and Mathematical Sciences"]}
+    ]
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Type != "monograph" || eprint.MonographType != "technical_report" {
+		t.Errorf("expected technical_report monograph, got %q, %q", eprint.Type, eprint.MonographType)
+	}
+	if eprint.Institution != "California Institute of Technology" {
+		t.Errorf("unexpected institution %q", eprint.Institution)
+	}
+	if eprint.Department != "Computing and Mathematical Sciences" {
+		t.Errorf("unexpected department %q", eprint.Department)
+	}
+	if eprint.PlaceOfPub != "Pasadena, CA" {
+		t.Errorf("unexpected place of pub %q", eprint.PlaceOfPub)
+	}
+}
+
+func TestCrossRefRules(t *testing.T) {
+	fName := path.Join(t.TempDir(), "crossref-rules.json")
+	src := []byte(`{"types": {"posted-content": "preprint"}, "issn_type": "electronic"}`)
+	if err := ioutil.WriteFile(fName, src, 0644); err != nil {
+		t.Errorf("can't write %q, %s", fName, err)
+		t.FailNow()
+	}
+	rules, err := LoadCrossRefRules(fName)
+	if err != nil {
+		t.Errorf("LoadCrossRefRules() returned an error, %s", err)
+		t.FailNow()
+	}
+	if rules.Types["journal-article"] != "article" {
+		t.Errorf("expected default type mappings to be kept, got %+v", rules.Types)
+	}
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "posted-content",
+    "title": ["A Preprint"],
+    "ISSN": ["1234-5679", "2345-678X"],
+    "issn-type": [
+      {"value": "1234-5679", "type": "print"},
+      {"value": "2345-678X", "type": "electronic"}
+    ]
+  }
+}`))
+	eprint, err := rules.WorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("WorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Type != "preprint" {
+		t.Errorf("expected type preprint, got %q", eprint.Type)
+	}
+	if eprint.ISSN != "2345-678X" {
+		t.Errorf("expected electronic ISSN, got %q", eprint.ISSN)
+	}
+	eprint, err = CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Type != "posted-content" || eprint.ISSN != "1234-5679" {
+		t.Errorf("expected default rules, got type %q, ISSN %q", eprint.Type, eprint.ISSN)
+	}
+}
+
+func TestCrossRefContributorOrder(t *testing.T) {
+	rules := DefaultCrossRefRules()
+	rules.ContributorRoles["chair"] = "http://www.loc.gov/loc.terms/relators/CHR"
+	rules.ContributorRoles["reviewer"] = "http://www.loc.gov/loc.terms/relators/REV"
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "book",
+    "title": ["A Book"],
+    "translator": [{"given": "Tessa", "family": "Translator"}],
+    "reviewer": [{"given": "Rita", "family": "Reviewer"}],
+    "chair": [{"given": "Carl", "family": "Chair"}]
+  }
+}`))
+	expected := []string{"Chair", "Reviewer", "Translator"}
+	for i := 0; i < 10; i++ {
+		eprint, err := rules.WorksToEPrint(obj)
+		if err != nil {
+			t.Errorf("WorksToEPrint() returned an error, %s", err)
+			t.FailNow()
+		}
+		if eprint.Contributors == nil || len(eprint.Contributors.Items) != len(expected) {
+			t.Errorf("expected %d contributors, got %+v", len(expected), eprint.Contributors)
+			t.FailNow()
+		}
+		for j, item := range eprint.Contributors.Items {
+			if item.Name.Family != expected[j] {
+				t.Errorf("expected contributor %d to be %q, got %q", j, expected[j], item.Name.Family)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestCrossRefAbstract(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "journal-article",
+    "title": ["An Article"],
+    "abstract": "<jats:title>Abstract</jats:title><jats:p>We measure <jats:italic>things</jats:italic>.</jats:p>"
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Abstract != "We measure things." {
+		t.Errorf("unexpected abstract %q", eprint.Abstract)
+	}
+	rules := DefaultCrossRefRules()
+	rules.AbstractFormat = "html"
+	eprint, err = rules.WorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("WorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Abstract != "<p>We measure <i>things</i>.</p>" {
+		t.Errorf("unexpected HTML abstract %q", eprint.Abstract)
+	}
+}
+
+func TestCrossRefFunders(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "journal-article",
+    "title": ["An Article"],
+    "funder": [
+      {"DOI": "10.13039/100000001", "name": "National Science Foundation", "award": ["AST-1234567"]},
+      {"name": "Example Foundation"}
+    ]
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Funders == nil || len(eprint.Funders.Items) != 2 {
+		t.Errorf("expected two funders, got %+v", eprint.Funders)
+		t.FailNow()
+	}
+	if doi := eprint.Funders.Items[0].FunderDOI; doi != "10.13039/100000001" {
+		t.Errorf("expected funder DOI, got %q", doi)
+	}
+	if doi := eprint.Funders.Items[1].FunderDOI; doi != "" {
+		t.Errorf("expected no funder DOI, got %q", doi)
+	}
+}
+
+func TestCrossRefUnexpectedValues(t *testing.T) {
+	obj := crossRefObject(t, []byte(`{
+  "message": {
+    "type": "proceedings-article",
+    "title": ["A Paper"],
+    "published-print": {"date-parts": [[null]]},
+    "event": "International Conference on Examples"
+  }
+}`))
+	eprint, err := CrossRefWorksToEPrint(obj)
+	if err != nil {
+		t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+		t.FailNow()
+	}
+	if eprint.Date != "" || eprint.EventTitle != "" {
+		t.Errorf("expected date and event to be skipped, got %q, %q", eprint.Date, eprint.EventTitle)
+	}
+	if s := crossRefDateParts([]interface{}{[]interface{}{json.Number("2019"), nil}}); s != "2019" {
+		t.Errorf("expected %q, got %q", "2019", s)
+	}
+
+	for _, src := range []string{
+		`{"message": {"type": "report", "institution": ["California Institute of Technology"]}}`,
+		`{"message": {"type": "report", "institution": [{"name": "California Institute of Technology", "department": "Computing", "place": "Pasadena, CA"}]}}`,
+		`{"message": {"type": "report", "institution": [{"name": "California Institute of Technology", "department": [], "place": []}]}}`,
+	} {
+		eprint, err := CrossRefWorksToEPrint(crossRefObject(t, []byte(src)))
+		if err != nil {
+			t.Errorf("CrossRefWorksToEPrint() returned an error, %s", err)
+			continue
+		}
+		if eprint.Department != "" || eprint.PlaceOfPub != "" {
+			t.Errorf("expected department and place to be skipped for %s, got %q, %q", src, eprint.Department, eprint.PlaceOfPub)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package eprinttools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// CrossRefRules holds the mappings used to convert CrossRef works
+// metadata into an EPrint. The defaults follow CaltechAUTHORS practice,
+// other repositories can adjust them with a rules file read by
+// LoadCrossRefRules.
+type CrossRefRules struct {
+	// Types maps CrossRef types to EPrint types
+	// (e.g. "journal-article" to "article"), unmapped types are
+	// passed through.
+	Types map[string]string `json:"types"`
+	// MonographTypes maps CrossRef types to the EPrint monograph type
+	// (e.g. "report" to "technical_report").
+	MonographTypes map[string]string `json:"monograph_types"`
+	// ContributorRoles maps CrossRef contributor lists to the Library
+	// of Congress relator terms EPrints uses as contributor type.
+	ContributorRoles map[string]string `json:"contributor_roles"`
+	// ISSNType is the preferred ISSN type ("print" or "electronic"),
+	// if empty or not available the first ISSN listed is used.
+	ISSNType string `json:"issn_type"`
+	// AbstractFormat is "text" (the default) or "html", CrossRef
+	// abstracts are JATS markup converted to this format.
+	AbstractFormat string `json:"abstract_format"`
+}
+
+// DefaultCrossRefRules returns the rules used by CrossRefWorksToEPrint
+func DefaultCrossRefRules() *CrossRefRules {
+	return &CrossRefRules{
+		Types: map[string]string{
+			//NOTE: This seems vary idiosyncratic to CaltechAUTHORS
+			"proceedings-article": "book_section",
+			"journal-article":     "article",
+			"book-chapter":        "book_section",
+			"report":              "monograph",
+		},
+		MonographTypes: map[string]string{
+			"report": "technical_report",
+		},
+		ContributorRoles: map[string]string{
+			"translator": "http://www.loc.gov/loc.terms/relators/TRL",
+		},
+	}
+}
+
+// LoadCrossRefRules reads a JSON rules file and returns the default
+// rules updated by it, e.g.
+//
+//	{"types": {"posted-content": "preprint"}, "issn_type": "electronic"}
+//
+// maps CrossRef's posted content to preprints and prefers electronic
+// ISSN, the other default mappings are kept.
+func LoadCrossRefRules(fName string) (*CrossRefRules, error) {
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return nil, err
+	}
+	rules := DefaultCrossRefRules()
+	if err := json.Unmarshal(src, &rules); err != nil {
+		return nil, fmt.Errorf("%s, %s", fName, err)
+	}
+	return rules, nil
+}
+
+// eprintType converts content type from CrossRef to EPrints
+// (e.g. "journal-article" to "article")
+func (rules *CrossRefRules) eprintType(s string) string {
+	if t, ok := rules.Types[strings.ToLower(s)]; ok == true && t != "" {
+		return t
+	}
+	return s
+}
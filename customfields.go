@@ -0,0 +1,111 @@
+package eprinttools
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// LoadCustomFieldSelectors reads a JSON object mapping custom field
+// names to element paths (see CustomFields()) from a file
+// (e.g. {"option_minor": "option_minor/item"}).
+func LoadCustomFieldSelectors(fName string) (map[string]string, error) {
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return nil, err
+	}
+	selectors := map[string]string{}
+	if err := json.Unmarshal(src, &selectors); err != nil {
+		return nil, err
+	}
+	return selectors, nil
+}
+
+// CustomFields extracts site specific elements from EPrint XML that
+// the EPrint struct doesn't map. selectors maps a field name to a
+// slash separated element path relative to <eprint> (e.g.
+// "patent_filing_date" or "option_minor/item"). A path matching one
+// element has a string value, one matching several elements has a
+// list of strings. Returns one map per eprint in document order.
+func CustomFields(src []byte, selectors map[string]string) ([]map[string]interface{}, error) {
+	var (
+		results []map[string]interface{}
+		fields  map[string][]string
+		stack   []string
+	)
+	paths := map[string][]string{}
+	for name, selector := range selectors {
+		p := strings.Trim(selector, "/")
+		paths[p] = append(paths[p], name)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(toValidUTF8(src)))
+	dec.CharsetReader = charsetReader
+	dec.Entity = xml.HTMLEntity
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if fields == nil {
+				if t.Name.Local == "eprint" {
+					fields = map[string][]string{}
+					stack = []string{}
+				}
+				continue
+			}
+			stack = append(stack, t.Name.Local)
+			if names, ok := paths[strings.Join(stack, "/")]; ok {
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return results, err
+				}
+				stack = stack[0 : len(stack)-1]
+				for _, name := range names {
+					fields[name] = append(fields[name], strings.TrimSpace(s))
+				}
+			}
+		case xml.EndElement:
+			if fields == nil {
+				continue
+			}
+			if len(stack) == 0 {
+				// NOTE: we've reached </eprint>
+				m := map[string]interface{}{}
+				for name, values := range fields {
+					if len(values) == 1 {
+						m[name] = values[0]
+					} else {
+						m[name] = values
+					}
+				}
+				results = append(results, m)
+				fields = nil
+				continue
+			}
+			stack = stack[0 : len(stack)-1]
+		}
+	}
+}
+
+// ApplyCustomFields populates the CustomFields of each EPrint using
+// selectors against src, the EPrint XML eprints was unmarshaled from.
+func (eprints *EPrints) ApplyCustomFields(src []byte, selectors map[string]string) error {
+	fields, err := CustomFields(src, selectors)
+	if err != nil {
+		return err
+	}
+	for i, e := range eprints.EPrint {
+		if i < len(fields) && len(fields[i]) > 0 {
+			e.CustomFields = fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package eprinttools
+
+import (
+	"testing"
+)
+
+func TestCustomFields(t *testing.T) {
+	src := []byte(`<?xml version='1.0' encoding='utf-8'?>
+<eprints xmlns='http://eprints.org/ep2/data/2.0'>
+  <eprint id='https://example.org/id/eprint/1'>
+    <eprintid>1</eprintid>
+    <title>A thesis</title>
+    <option_minor><item>physics</item><item>chemistry</item></option_minor>
+    <patent_filing_date>2020-01-01</patent_filing_date>
+  </eprint>
+  <eprint id='https://example.org/id/eprint/2'>
+    <eprintid>2</eprintid>
+    <title>No custom fields</title>
+  </eprint>
+</eprints>`)
+	selectors := map[string]string{
+		"option_minor":       "option_minor/item",
+		"patent_filing_date": "/patent_filing_date",
+	}
+	eprints := new(EPrints)
+	if err := DecodeXML(src, &eprints); err != nil {
+		t.Errorf("DecodeXML() returned an error, %s", err)
+		t.FailNow()
+	}
+	if err := eprints.ApplyCustomFields(src, selectors); err != nil {
+		t.Errorf("ApplyCustomFields() returned an error, %s", err)
+		t.FailNow()
+	}
+	fields := eprints.EPrint[0].CustomFields
+	if s, ok := fields["patent_filing_date"]; ok == false || s != "2020-01-01" {
+		t.Errorf("expected patent_filing_date 2020-01-01, got %+v", fields)
+	}
+	if l, ok := fields["option_minor"].([]string); ok == false || len(l) != 2 || l[0] != "physics" || l[1] != "chemistry" {
+		t.Errorf("expected option_minor [physics chemistry], got %+v", fields)
+	}
+	if eprints.EPrint[1].CustomFields != nil {
+		t.Errorf("expected no custom fields, got %+v", eprints.EPrint[1].CustomFields)
+	}
+}
@@ -0,0 +1,94 @@
+package eprinttools
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	// Caltech Library packages
+	"github.com/caltechlibrary/eprinttools/rc"
+)
+
+// GetDocuments returns the documents of the EPrint record uri (e.g.
+// /rest/eprint/1234.xml or 1234) including their files. Records of
+// any status are accepted.
+func (api *EPrintsAPI) GetDocuments(uri string) (DocumentList, error) {
+	eprint, _, err := api.GetEPrint(eprintURI(uri))
+	if eprint == nil {
+		return nil, err
+	}
+	if eprint.Documents == nil {
+		return DocumentList{}, nil
+	}
+	return *eprint.Documents, nil
+}
+
+// downloadTimeout is the time allowed to download a file when the
+// EPrintsAPI doesn't have its own Client
+const downloadTimeout = 10 * time.Minute
+
+// DownloadFile writes the content of f to out. When f is on the same
+// host as the REST API the repository's credentials are used so
+// restricted files can be retrieved, otherwise it is fetched anonymously.
+func (api *EPrintsAPI) DownloadFile(f *File, out io.Writer) error {
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return err
+	}
+	//NOTE: the file URL may be on another host than the REST API,
+	// only send the credentials to the repository itself
+	authType, username, secret := rc.AuthNone, "", ""
+	if u.Scheme == api.URL.Scheme && strings.EqualFold(u.Host, api.URL.Host) {
+		authType, username, secret = api.AuthType, api.Username, api.Secret
+	}
+	rest, err := rc.New(f.URL, authType, username, secret)
+	if err != nil {
+		return err
+	}
+	//NOTE: files can be much larger than REST API records, without
+	// the caller's client allow for a slow download
+	rest.Timeout = downloadTimeout
+	rest.Client = api.Client
+	body, err := rest.Stream("GET", u.RequestURI(), map[string]string{})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(out, body)
+	return err
+}
+
+// UploadFile adds a file named fName of mimeType read from src to the
+// document docID using the EPrints CRUD interface
+// (/id/document/<docid>/contents). If replace is true the document's
+// existing files are replaced, e.g. to fix a corrupted PDF.
+func (api *EPrintsAPI) UploadFile(docID int, fName string, mimeType string, src io.Reader, replace bool) error {
+	rest, err := api.restClient()
+	if err != nil {
+		return err
+	}
+	rest.AddHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(fName)}))
+	method := "POST"
+	if replace {
+		method = "PUT"
+	}
+	p := path.Join(api.URL.Path, "id", "document", fmt.Sprintf("%d", docID), "contents")
+	_, err = rest.Send(method, p, mimeType, src)
+	return err
+}
+
+// DeleteFile removes the file fileID using the EPrints CRUD interface
+// (/id/file/<fileid>).
+func (api *EPrintsAPI) DeleteFile(fileID int) error {
+	rest, err := api.restClient()
+	if err != nil {
+		return err
+	}
+	p := path.Join(api.URL.Path, "id", "file", fmt.Sprintf("%d", fileID))
+	_, err = rest.Send("DELETE", p, "", nil)
+	return err
+}
@@ -0,0 +1,123 @@
+package eprinttools
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocumentFiles(t *testing.T) {
+	requests := []string{}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/rest/eprint/1234.xml":
+			fmt.Fprintf(w, `<eprints><eprint><eprintid>1234</eprintid><eprint_status>archive</eprint_status>
+<documents><document><docid>10</docid><pos>1</pos><main>a.pdf</main>
+<files><file><fileid>20</fileid><filename>a.pdf</filename><url>%s/1234/1/a.pdf</url></file></files>
+</document></documents></eprint></eprints>`, ts.URL)
+		case r.Method == "GET" && r.URL.Path == "/1234/1/a.pdf":
+			fmt.Fprintf(w, "%%PDF-1.4")
+		case r.Method == "PUT" && r.URL.Path == "/id/document/10/contents":
+			src, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, fmt.Sprintf("%s %s %s %s %s", r.Method, r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Content-Disposition"), src))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "DELETE" && r.URL.Path == "/id/file/20":
+			requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	docs, err := api.GetDocuments("1234")
+	if err != nil {
+		t.Errorf("GetDocuments() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(docs) != 1 || docs[0].DocID != 10 || len(docs[0].Files) != 1 {
+		t.Errorf("unexpected documents %+v", docs)
+		t.FailNow()
+	}
+	buf := new(bytes.Buffer)
+	if err := api.DownloadFile(docs[0].Files[0], buf); err != nil {
+		t.Errorf("DownloadFile() returned an error, %s", err)
+	} else if buf.String() != "%PDF-1.4" {
+		t.Errorf("unexpected file content %q", buf.String())
+	}
+	if err := api.UploadFile(10, "/tmp/a.pdf", "application/pdf", strings.NewReader("%PDF-1.5"), true); err != nil {
+		t.Errorf("UploadFile() returned an error, %s", err)
+	}
+	if err := api.DeleteFile(20); err != nil {
+		t.Errorf("DeleteFile() returned an error, %s", err)
+	}
+	expected := []string{
+		"PUT /id/document/10/contents application/pdf attachment; filename=a.pdf %PDF-1.5",
+		"DELETE /id/file/20",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected requests %q, got %q", expected, requests)
+	}
+}
+
+func TestDownloadFileCredentials(t *testing.T) {
+	auth := map[string]bool{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, ok := r.BasicAuth()
+		auth[r.Host] = ok
+		fmt.Fprintf(w, "%%PDF-1.4")
+	})
+	repo := httptest.NewServer(handler)
+	defer repo.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	api, err := New(repo.URL, false, "basic", "jane", "secret")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	for _, u := range []string{repo.URL + "/1234/1/a.pdf", other.URL + "/a.pdf"} {
+		if err := api.DownloadFile(&File{URL: u}, new(bytes.Buffer)); err != nil {
+			t.Errorf("DownloadFile(%q) returned an error, %s", u, err)
+		}
+	}
+	if auth[strings.TrimPrefix(repo.URL, "http://")] == false {
+		t.Errorf("expected credentials to be sent to the repository")
+	}
+	if auth[strings.TrimPrefix(other.URL, "http://")] == true {
+		t.Errorf("expected no credentials to be sent to another host")
+	}
+}
+
+func TestDownloadFileRequestURI(t *testing.T) {
+	requested := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		fmt.Fprintf(w, "%%PDF-1.4")
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	expected := "/1234/1/a%2Fb.pdf?version=2&download=%7e"
+	if err := api.DownloadFile(&File{URL: ts.URL + expected}, new(bytes.Buffer)); err != nil {
+		t.Errorf("DownloadFile() returned an error, %s", err)
+	}
+	if requested != expected {
+		t.Errorf("expected %q, got %q", expected, requested)
+	}
+}
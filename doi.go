@@ -0,0 +1,93 @@
+package eprinttools
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	// Caltech Library packages
+	"github.com/caltechlibrary/eprinttools/rc"
+)
+
+// NormalizeDOI returns a DOI in a form suitable for comparison, the
+// URL and "doi:" prefixes are removed and it is lower cased (DOI are
+// case insensitive), e.g. "https://doi.org/10.1021/ACSAMI.7b15651"
+// becomes "10.1021/acsami.7b15651".
+func NormalizeDOI(s string) string {
+	doi := strings.TrimSpace(s)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(strings.ToLower(doi), prefix) {
+			doi = doi[len(prefix):]
+			break
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(doi))
+}
+
+// DefaultDOISearch is the EPrints search FindDOI uses, {doi} is
+// replaced by the DOI. The results are exported as EPrint XML.
+const DefaultDOISearch = "/cgi/search/archive/advanced/export_XML.xml?screen=Search&dataset=archive&_action_export=1&output=XML&doi={doi}&doi_merge=ALL&satisfyall=ALL"
+
+// hasDOI returns true if doi matches the EPrint's doi field or one of
+// its related URL of type "doi"
+func (e *EPrint) hasDOI(doi string) bool {
+	doi = NormalizeDOI(doi)
+	if doi == "" {
+		return false
+	}
+	if NormalizeDOI(e.DOI) == doi {
+		return true
+	}
+	if e.RelatedURL != nil {
+		for _, item := range e.RelatedURL.Items {
+			if strings.ToLower(item.Type) == "doi" && NormalizeDOI(item.URL) == doi {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindDOI searches the repository for doi using api.DOISearch (or
+// DefaultDOISearch) and returns the eprint URI (e.g.
+// /rest/eprint/1234.xml) of the records whose doi field or related
+// URL match it.
+func (api *EPrintsAPI) FindDOI(doi string) ([]string, error) {
+	search := api.DOISearch
+	if search == "" {
+		search = DefaultDOISearch
+	}
+	u, err := url.Parse(strings.Replace(search, "{doi}", url.QueryEscape(strings.TrimSpace(doi)), -1))
+	if err != nil {
+		return nil, fmt.Errorf("DOI search %q, %s", search, err)
+	}
+	payload := map[string]string{}
+	for key, values := range u.Query() {
+		if len(values) > 0 {
+			payload[key] = values[0]
+		}
+	}
+	rest, err := rc.New(api.URL.String(), api.AuthType, api.Username, api.Secret)
+	if err != nil {
+		return nil, err
+	}
+	rest.Timeout = 30 * time.Second
+	rest.Client = api.Client
+	src, err := rest.Request("GET", path.Join(api.URL.Path, u.Path), payload)
+	if err != nil {
+		return nil, fmt.Errorf("searching for %s, %s", doi, err)
+	}
+	data := new(EPrints)
+	if err := DecodeXML(src, &data); err != nil {
+		return nil, fmt.Errorf("searching for %s, %s", doi, err)
+	}
+	uris := []string{}
+	for _, e := range data.EPrint {
+		if e.hasDOI(doi) {
+			uris = append(uris, fmt.Sprintf("/rest/eprint/%d.xml", e.EPrintID))
+		}
+	}
+	return uris, nil
+}
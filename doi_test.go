@@ -0,0 +1,59 @@
+package eprinttools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDOI(t *testing.T) {
+	expected := "10.1021/acsami.7b15651"
+	for _, s := range []string{
+		"10.1021/acsami.7b15651",
+		" 10.1021/ACSAMI.7b15651\n",
+		"https://doi.org/10.1021/acsami.7b15651",
+		"http://dx.doi.org/10.1021/acsami.7b15651",
+		"doi:10.1021/acsami.7b15651",
+	} {
+		if doi := NormalizeDOI(s); doi != expected {
+			t.Errorf("NormalizeDOI(%q) expected %q, got %q", s, expected, doi)
+		}
+	}
+}
+
+func TestFindDOI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cgi/search/archive/advanced/export_XML.xml" || r.URL.Query().Get("doi") != "10.1021/acsami.7b15651" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version='1.0' encoding='utf-8'?>
+<eprints>
+<eprint id="1"><eprintid>1</eprintid><doi>10.1021/ACSAMI.7b15651</doi></eprint>
+<eprint id="2"><eprintid>2</eprintid><related_url><item><url>https://doi.org/10.1021/acsami.7b15651</url><type>doi</type></item></related_url></eprint>
+<eprint id="3"><eprintid>3</eprintid><doi>10.1021/acsami.7b15651.s001</doi></eprint>
+</eprints>`)
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	uris, err := api.FindDOI("10.1021/acsami.7b15651")
+	if err != nil {
+		t.Errorf("FindDOI() returned an error, %s", err)
+		t.FailNow()
+	}
+	if strings.Join(uris, " ") != "/rest/eprint/1.xml /rest/eprint/2.xml" {
+		t.Errorf("unexpected uris %+v", uris)
+	}
+
+	api.DOISearch = "/cgi/search/missing?doi={doi}"
+	if _, err := api.FindDOI("10.1021/acsami.7b15651"); err == nil {
+		t.Errorf("expected an error for a failed search")
+	}
+}
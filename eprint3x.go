@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"os"
@@ -38,6 +39,15 @@ const (
 	maxConsecutiveFailedRequests = 10
 )
 
+var (
+	// DefaultEPrintStatus holds the eprint_status values returned
+	// without a warning by GetEPrints() and EPrintsAPI.GetEPrint()
+	// when the caller doesn't give the statuses to include.
+	// The other statuses EPrints uses are "buffer" (review queue),
+	// "inbox" (user workarea) and "deletion" (retired).
+	DefaultEPrintStatus = []string{"archive"}
+)
+
 //
 // NOTE: This file contains the general structure in Caltech Libraries EPrints 3.x based repositories.
 //
@@ -88,6 +98,7 @@ type EPrint struct {
 	EventTitle           string                        `xml:"event_title,omitempty" json:"event_title,omitempty"`
 	EventLocation        string                        `xml:"event_location,omitempty" json:"event_location,omitempty"`
 	EventDates           string                        `xml:"event_dates,omitempty" json:"event_dates,omitempty"`
+	EventType            string                        `xml:"event_type,omitempty" json:"event_type,omitempty"`
 	IDNumber             string                        `xml:"id_number,omitempty" json:"id_number,omitempty"`
 	Refereed             string                        `xml:"refereed,omitempty" json:"refereed,omitempty"`
 	ISBN                 string                        `xml:"isbn,omitempty" json:"isbn,omitempty"`
@@ -194,6 +205,11 @@ type EPrint struct {
 	// EPrints field data to other JSON formats.
 	PrimaryObject  map[string]interface{}   `xml:"-" json:"primary_object,omitempty"`
 	RelatedObjects []map[string]interface{} `xml:"-" json:"related_objects,omitempty"`
+	AccessRights   string                   `xml:"-" json:"access_rights,omitempty"`
+
+	// CustomFields holds site specific EPrint fields not otherwise
+	// mapped, see CustomFields() and EPrints.ApplyCustomFields()
+	CustomFields map[string]interface{} `xml:"-" json:"custom_fields,omitempty"`
 }
 
 // Item is a generic type used by various fields (e.g. Creator, Division, OptionMajor)
@@ -211,6 +227,8 @@ type Item struct {
 	GrantNumber string   `xml:"grant_number,omitempty" json:"grant_number,omitempty"`
 	URI         string   `xml:"uri,omitempty" json:"uri,omitempty"`
 	ORCID       string   `xml:"orcid,omitempty" json:"orcid,omitempty"`
+	FunderDOI   string   `xml:"-" json:"funder_doi,omitempty"`
+	ROR         string   `xml:"-" json:"ror,omitempty"`
 	Value       string   `xml:",chardata" json:"value,omitempty"`
 }
 
@@ -262,8 +280,19 @@ func (item *Item) MarshalJSON() ([]byte, error) {
 		m["uri"] = item.URI
 		flatten = false
 	}
+	if strings.TrimSpace(item.FunderDOI) != "" {
+		m["funder_doi"] = item.FunderDOI
+		flatten = false
+	}
+	if strings.TrimSpace(item.ROR) != "" {
+		m["ror"] = item.ROR
+		flatten = false
+	}
 	if s := strings.TrimSpace(item.ORCID); s != "" {
-		//FIXME: should validate the orcid to avoid legacy data issues
+		//NOTE: legacy ORCID that fail validation are passed through as is
+		if orcid, err := NormalizeORCID(s); err == nil {
+			s = orcid
+		}
 		m["orcid"] = s
 		flatten = false
 	}
@@ -331,6 +360,10 @@ func (item *Item) UnmarshalJSON(src []byte) error {
 			item.URI = value.(string)
 		case "orcid":
 			item.ORCID = value.(string)
+		case "funder_doi":
+			item.FunderDOI = value.(string)
+		case "ror":
+			item.ROR = value.(string)
 		case "value":
 			item.Value = value.(string)
 		}
@@ -846,7 +879,9 @@ func (eprints *EPrints) AddEPrint(eprint *EPrint) int {
 
 // GetEPrints retrieves an EPrint record (e.g. via REST API)
 // A populated EPrints structure, the raw XML and an error.
-func GetEPrints(baseURL string, authType int, username string, secret string, key string) (*EPrints, []byte, error) {
+// If the record's eprint_status isn't one of includeStatus (default
+// DefaultEPrintStatus) the record is returned with a warning error.
+func GetEPrints(baseURL string, authType int, username string, secret string, key string, includeStatus ...string) (*EPrints, []byte, error) {
 	workURL, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, nil, err
@@ -869,11 +904,14 @@ func GetEPrints(baseURL string, authType int, username string, secret string, ke
 	}
 
 	rec := new(EPrints)
-	err = xml.Unmarshal(content, &rec)
+	err = DecodeXML(content, &rec)
 	if err != nil {
 		return nil, content, err
 	}
-	if len(rec.EPrint) > 0 && (rec.EPrint[0].EPrintStatus == "deletion" || rec.EPrint[0].EPrintStatus == "inbox" || rec.EPrint[0].EPrintStatus == "buffer") {
+	if len(includeStatus) == 0 {
+		includeStatus = DefaultEPrintStatus
+	}
+	if len(rec.EPrint) > 0 && rec.EPrint[0].HasStatus(includeStatus...) == false {
 		return rec, content, fmt.Errorf("WARNING status %s %s", rec.EPrint[0].ID, rec.EPrint[0].EPrintStatus)
 	}
 	return rec, content, nil
@@ -898,20 +936,11 @@ func GetKeys(baseURL string, authType int, username string, secret string) ([]st
 	// Switch to use Rest Client Wrapper
 	rest, err := rc.New(workURL.String(), authType, username, secret)
 	if err != nil {
-		return nil, fmt.Errorf("requesting %s, %s", workURL.String(), err)
-	}
-	content, err := rest.Request("GET", workURL.Path, map[string]string{})
-	if err != nil {
-		return nil, fmt.Errorf("requested %s, %s", workURL.String(), err)
-	}
-	eIDs := new(ePrintIDs)
-	err = xml.Unmarshal(content, &eIDs)
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("requesting %s, %s", workURL.Redacted(), err)
 	}
 	// Build a list of Unique IDs in a map, then convert unique querys to results array
 	m := make(map[string]bool)
-	for _, val := range eIDs.IDs {
+	err = walkEPrintIDPages(rest, workURL.Path, func(val string) error {
 		if strings.HasSuffix(val, ".xml") == true {
 			eprintID := strings.TrimSuffix(val, ".xml")
 			if _, hasID := m[eprintID]; hasID == false {
@@ -921,6 +950,10 @@ func GetKeys(baseURL string, authType int, username string, secret string) ([]st
 				results = append(results, eprintID)
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("requested %s, %s", workURL.Redacted(), err)
 	}
 	return results, nil
 }
@@ -945,7 +978,7 @@ func GetModifiedKeys(baseURL string, authType int, username string, secret strin
 	// Switch to use Rest Client Wrapper
 	rest, err := rc.New(baseURL, authType, username, secret)
 	if err != nil {
-		return nil, fmt.Errorf("requesting %s, %s", baseURL, err)
+		return nil, fmt.Errorf("requesting %s, %s", workURL.Redacted(), err)
 	}
 
 	// Pass baseURL to GetKeys(), get key list then filter for modified times.
@@ -1001,23 +1034,24 @@ type File struct {
 
 // Document structures inside a Record (i.e. <eprint>...<documents><document>...</document>...</documents>...</eprint>)
 type Document struct {
-	XMLName    xml.Name `json:"-"`
-	ID         string   `xml:"id,attr" json:"id"`
-	DocID      int      `xml:"docid" json:"doc_id"`
-	RevNumber  int      `xml:"rev_number" json:"rev_number,omitempty"`
-	Files      []*File  `xml:"files>file" json:"files,omitempty"`
-	EPrintID   int      `xml:"eprintid" json:"eprint_id"`
-	Pos        int      `xml:"pos" json:"pos,omitempty"`
-	Placement  int      `xml:"placement,omitempty" json:"placement,omitempty"`
-	MimeType   string   `xml:"mime_type" json:"mime_type"`
-	Format     string   `xml:"format" json:"format"`
-	FormatDesc string   `xml:"formatdesc,omitempty" json:"format_desc,omitempty"`
-	Language   string   `xml:"language,omitempty" json:"language,omitempty"`
-	Security   string   `xml:"security" json:"security"`
-	License    string   `xml:"license" json:"license"`
-	Main       string   `xml:"main" json:"main"`
-	Content    string   `xml:"content,omitempty" json:"content,omitempty"`
-	Relation   []*Item  `xml:"relation>item,omitempty" json:"relation,omitempty"`
+	XMLName     xml.Name `json:"-"`
+	ID          string   `xml:"id,attr" json:"id"`
+	DocID       int      `xml:"docid" json:"doc_id"`
+	RevNumber   int      `xml:"rev_number" json:"rev_number,omitempty"`
+	Files       []*File  `xml:"files>file" json:"files,omitempty"`
+	EPrintID    int      `xml:"eprintid" json:"eprint_id"`
+	Pos         int      `xml:"pos" json:"pos,omitempty"`
+	Placement   int      `xml:"placement,omitempty" json:"placement,omitempty"`
+	MimeType    string   `xml:"mime_type" json:"mime_type"`
+	Format      string   `xml:"format" json:"format"`
+	FormatDesc  string   `xml:"formatdesc,omitempty" json:"format_desc,omitempty"`
+	Language    string   `xml:"language,omitempty" json:"language,omitempty"`
+	Security    string   `xml:"security" json:"security"`
+	DateEmbargo string   `xml:"date_embargo,omitempty" json:"date_embargo,omitempty"`
+	License     string   `xml:"license" json:"license"`
+	Main        string   `xml:"main" json:"main"`
+	Content     string   `xml:"content,omitempty" json:"content,omitempty"`
+	Relation    []*Item  `xml:"relation>item,omitempty" json:"relation,omitempty"`
 }
 
 // DocumentList is an array of pointers to Document structs
@@ -1043,10 +1077,122 @@ func (documentList DocumentList) IndexOf(i int) *Document {
 	return documentList[i]
 }
 
-// ePrintIDs is a struct for parsing the ids page of EPrints REST API
-type ePrintIDs struct {
-	XMLName xml.Name `xml:"html" json:"-"`
-	IDs     []string `xml:"body>ul>li>a" json:"ids"`
+// readEPrintIDs reads the HTML page of eprint ids returned by the
+// EPrints REST API (e.g. /rest/eprint/) as a token stream, calling fn
+// with the text of each link. The decoder is lenient so malformed
+// HTML doesn't abort the read and the whole page is never held
+// in memory as a tree.
+func readEPrintIDs(r io.Reader, fn func(string) error) error {
+	_, err := readEPrintIDPage(r, fn)
+	return err
+}
+
+// readEPrintIDPage reads a page of eprint ids like readEPrintIDs and
+// returns the href of the page's next page link (an a or link element
+// with rel="next"), or an empty string if it is the last page.
+func readEPrintIDPage(r io.Reader, fn func(string) error) (string, error) {
+	var (
+		inAnchor bool
+		text     strings.Builder
+		next     string
+	)
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return next, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "a" {
+				inAnchor = true
+				text.Reset()
+			}
+			if (name == "a" || name == "link") && next == "" {
+				rel, href := "", ""
+				for _, attr := range t.Attr {
+					switch strings.ToLower(attr.Name.Local) {
+					case "rel":
+						rel = attr.Value
+					case "href":
+						href = attr.Value
+					}
+				}
+				for _, val := range strings.Fields(strings.ToLower(rel)) {
+					if val == "next" {
+						next = strings.TrimSpace(href)
+					}
+				}
+			}
+		case xml.CharData:
+			if inAnchor {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if inAnchor && strings.ToLower(t.Name.Local) == "a" {
+				inAnchor = false
+				if err := fn(strings.TrimSpace(text.String())); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+}
+
+// walkEPrintIDPages reads the pages of eprint ids starting at docPath
+// calling fn with the text of each link. Next page links are followed
+// until the listing is exhausted, only the path and query of a link
+// are used so requests stay on the repository's host.
+func walkEPrintIDPages(rest *rc.RestAPI, docPath string, fn func(string) error) error {
+	seen := map[string]bool{}
+	for docPath != "" && seen[docPath] == false {
+		seen[docPath] = true
+		body, err := rest.Stream("GET", docPath, map[string]string{})
+		if err != nil {
+			return err
+		}
+		next, err := readEPrintIDPage(body, fn)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			break
+		}
+		base, err := url.Parse(docPath)
+		if err != nil {
+			return err
+		}
+		ref, err := url.Parse(next)
+		if err != nil {
+			return fmt.Errorf("next page %q, %s", next, err)
+		}
+		docPath = base.ResolveReference(ref).RequestURI()
+	}
+	return nil
+}
+
+// HasStatus returns true if the EPrint's eprint_status matches one
+// of the statuses provided. Records without an eprint_status are
+// treated as "archive".
+func (e *EPrint) HasStatus(statuses ...string) bool {
+	status := strings.TrimSpace(e.EPrintStatus)
+	if status == "" {
+		status = "archive"
+	}
+	for _, s := range statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
 }
 
 // SyntheticFields renders analyzes an EPrint object
@@ -1067,6 +1213,10 @@ func (e *EPrint) SyntheticFields() {
 				obj["mime_type"] = doc.MimeType
 				obj["content"] = doc.Content
 				obj["license"] = doc.License
+				if info, ok := NormalizeLicense(doc.License); ok == true {
+					obj["license_spdx"] = info.SPDX
+					obj["license_url"] = info.URL
+				}
 				if doc.Files != nil {
 					for _, fObj := range doc.Files {
 						if fObj.Filename == doc.Main {
@@ -1085,3 +1235,44 @@ func (e *EPrint) SyntheticFields() {
 		}
 	}
 }
+
+// rewriteURL replaces the scheme and host of u with base keeping the
+// path, e.g. "https://example.org/1/1/a.pdf" with a base of
+// "https://files.example.edu/authors" becomes
+// "https://files.example.edu/authors/1/1/a.pdf".
+func rewriteURL(u string, base string) (string, error) {
+	if u == "" {
+		return u, nil
+	}
+	src, err := url.Parse(u)
+	if err != nil {
+		return u, err
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(src.EscapedPath(), "/"), nil
+}
+
+// RewriteFileURLs changes the URLs of the document files, and of the
+// primary and related objects if SyntheticFields has been called, to
+// use base instead of the EPrints site (e.g. when the files are
+// served from a file server or S3 bucket).
+func (e *EPrint) RewriteFileURLs(base string) error {
+	var err error
+	if e.Documents != nil {
+		for _, doc := range *e.Documents {
+			for _, f := range doc.Files {
+				if f.URL, err = rewriteURL(f.URL, base); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	objects := append([]map[string]interface{}{e.PrimaryObject}, e.RelatedObjects...)
+	for _, obj := range objects {
+		if u, ok := obj["url"].(string); ok == true {
+			if obj["url"], err = rewriteURL(u, base); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
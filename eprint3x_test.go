@@ -22,6 +22,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
@@ -1239,3 +1241,140 @@ Wei Zhu (祝伟) et al. 2015 ApJ 805 8</official_cit>
 		t.Errorf("expected %q, got %q", "1", record.Number)
 	}
 }
+
+func TestHasStatus(t *testing.T) {
+	e := new(EPrint)
+	if e.HasStatus(DefaultEPrintStatus...) == false {
+		t.Errorf("expected empty eprint_status to be treated as archive")
+	}
+	for _, status := range []string{"buffer", "inbox", "deletion"} {
+		e.EPrintStatus = status
+		if e.HasStatus(DefaultEPrintStatus...) {
+			t.Errorf("expected %q to be excluded by default", status)
+		}
+		if e.HasStatus("archive", status) == false {
+			t.Errorf("expected %q to be included when requested", status)
+		}
+	}
+}
+
+func TestReadEPrintIDs(t *testing.T) {
+	// NOTE: unclosed list items and an HTML entity are not valid XML
+	src := `<html><head><title>EPrints &nbsp; REST</title></head><body>
+<ul>
+<li><a href='1.xml'>1.xml</a>
+<li><a href='1/'>1/</a>
+<li><a href='23.xml'>23.xml</a>
+<br>
+</ul></body></html>`
+	expected := []string{"1.xml", "1/", "23.xml"}
+	ids := []string{}
+	err := readEPrintIDs(strings.NewReader(src), func(val string) error {
+		ids = append(ids, val)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("readEPrintIDs() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(ids) != len(expected) {
+		t.Errorf("expected %d ids, got %d -> %+v", len(expected), len(ids), ids)
+		t.FailNow()
+	}
+	for i, val := range expected {
+		if ids[i] != val {
+			t.Errorf("expected %q, got %q", val, ids[i])
+		}
+	}
+}
+
+func TestReadEPrintIDPage(t *testing.T) {
+	src := `<html><head><link rel="prev" href="/rest/eprint/?page=1"><link rel="next" href="/rest/eprint/?page=3"></head>
+<body><a href='5.xml'>5.xml</a></body></html>`
+	ids := []string{}
+	next, err := readEPrintIDPage(strings.NewReader(src), func(val string) error {
+		ids = append(ids, val)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("readEPrintIDPage() returned an error, %s", err)
+		t.FailNow()
+	}
+	if next != "/rest/eprint/?page=3" {
+		t.Errorf("expected next page /rest/eprint/?page=3, got %q", next)
+	}
+	if len(ids) != 1 || ids[0] != "5.xml" {
+		t.Errorf("expected [5.xml], got %+v", ids)
+	}
+}
+
+func TestRewriteFileURLs(t *testing.T) {
+	e := new(EPrint)
+	e.ID = "https://example.org/id/eprint/1"
+	e.Documents = &DocumentList{
+		&Document{
+			Pos:      1,
+			Main:     "a paper.pdf",
+			Security: "public",
+			Files: []*File{
+				&File{Filename: "a paper.pdf", URL: "https://example.org/1/1/a%20paper.pdf"},
+			},
+		},
+	}
+	e.SyntheticFields()
+	if err := e.RewriteFileURLs("https://files.example.edu/authors/"); err != nil {
+		t.Errorf("RewriteFileURLs() returned an error, %s", err)
+		t.FailNow()
+	}
+	expected := "https://files.example.edu/authors/1/1/a%20paper.pdf"
+	if u := (*e.Documents)[0].Files[0].URL; u != expected {
+		t.Errorf("expected file URL %q, got %q", expected, u)
+	}
+	expected = "https://files.example.edu/authors/1/1/a%20paper.pdf"
+	if u := e.PrimaryObject["url"]; u != expected {
+		t.Errorf("expected primary object URL %q, got %q", expected, u)
+	}
+}
+
+func TestGetEPrintsStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<eprints><eprint><eprintid>1</eprintid><eprint_status>buffer</eprint_status></eprint></eprints>`)
+	}))
+	defer ts.Close()
+
+	if _, _, err := GetEPrints(ts.URL, rc.AuthNone, "", "", "1"); err == nil {
+		t.Errorf("expected a warning for a buffer record with the default status")
+	}
+	if _, _, err := GetEPrints(ts.URL, rc.AuthNone, "", "", "1", "archive", "buffer"); err != nil {
+		t.Errorf("expected buffer record to be included, %s", err)
+	}
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	api.IncludeStatus[0] = "buffer"
+	if DefaultEPrintStatus[0] != "archive" {
+		t.Errorf("changing IncludeStatus changed DefaultEPrintStatus, %+v", DefaultEPrintStatus)
+		DefaultEPrintStatus[0] = "archive"
+	}
+}
+
+func TestGetKeysRedactsURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	u.User = url.UserPassword("jane", "secret")
+	_, err := GetKeys(u.String(), rc.BasicAuth, "jane", "secret")
+	if err == nil {
+		t.Errorf("expected an error from GetKeys()")
+		t.FailNow()
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected the password to be redacted, %s", err)
+	}
+}
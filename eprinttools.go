@@ -19,14 +19,21 @@
 package eprinttools
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	// Caltech Library packages
@@ -60,6 +67,31 @@ type EPrintsAPI struct {
 	// SuppressSuggestions suppresses the Suggestions field
 	// NOTE: Bibs at Caltech Library use Suggestions as notes in CaltechTHESIS
 	SuppressSuggestions bool
+	// XMLArchive if set is a directory where GetEPrint saves the
+	// raw EPrint XML of each record retrieved (e.g. xml/1234.xml) so
+	// records can be re-parsed later without harvesting again.
+	XMLArchive string
+	// CustomFieldSelectors maps custom field names to element paths
+	// GetEPrint uses to populate EPrint.CustomFields
+	CustomFieldSelectors map[string]string
+	// DOISearch is the search FindDOI uses, defaults to DefaultDOISearch
+	DOISearch string
+	// IDSearch if set is the search WalkEPrintsURI lists eprint ids
+	// with instead of the REST API index (e.g. DefaultIDSearch)
+	IDSearch string
+	// IncludeStatus holds the eprint_status values GetEPrint will
+	// return without a warning, defaults to DefaultEPrintStatus.
+	IncludeStatus []string
+	// Validators if not nil makes GetEPrint send conditional requests
+	// for records it has seen before (see rc.LoadValidators). When
+	// a record hasn't changed GetEPrint reads it from XMLArchive if set
+	// otherwise it returns rc.ErrNotModified.
+	Validators map[string]rc.Validator
+	// Cache if not nil is used by the REST client to store and reuse
+	// responses (e.g. during development or repeated partial harvests)
+	Cache *rc.Cache
+	// Client if not nil is the HTTP client shared by REST requests
+	Client *http.Client
 }
 
 func normalizeDate(in string) string {
@@ -120,6 +152,7 @@ func New(eprintURL string, suppressSuggestions bool, authMethod, userName, userS
 	// Setup required
 	api := new(EPrintsAPI)
 	api.SuppressSuggestions = suppressSuggestions
+	api.IncludeStatus = append([]string{}, DefaultEPrintStatus...)
 
 	if eprintURL == "" {
 		eprintURL = "http://localhost:8080"
@@ -161,10 +194,36 @@ func (api *EPrintsAPI) ListEPrintsURI() ([]string, error) {
 	var (
 		results []string
 	)
+	err := api.WalkEPrintsURI(func(uri string) error {
+		results = append(results, uri)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DefaultIDSearch is an EPrints search listing every record in the
+// live archive by eprint id, exported with the Ids plugin (one id per
+// line). Repositories whose advanced search has no eprintid field need
+// their own search.
+const DefaultIDSearch = "/cgi/search/archive/advanced/export_Ids.txt?screen=Search&dataset=archive&_action_export=1&output=Ids&eprintid=1-&satisfyall=ALL&order=eprintid"
 
+// WalkEPrintsURI streams the list of eprint records from the EPrints
+// REST API calling fn with each unique eprint URI (e.g.
+// /rest/eprint/1234.xml). If fn returns an error the walk stops and
+// that error is returned. This avoids holding the full id list for
+// very large repositories in memory. Paged listings are followed to
+// the last page. If api.IDSearch is set the ids are listed with that
+// search instead, only records the search finds are walked.
+func (api *EPrintsAPI) WalkEPrintsURI(fn func(string) error) error {
+	if api.IDSearch != "" {
+		return api.walkIDSearch(fn)
+	}
 	workingURL, err := url.Parse(api.URL.String())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if workingURL.Path == "" {
 		workingURL.Path = path.Join("rest", "eprint") + "/"
@@ -174,41 +233,80 @@ func (api *EPrintsAPI) ListEPrintsURI() ([]string, error) {
 	}
 	// Switch to use Rest Client Wrapper
 	rest, err := rc.New(workingURL.String(), api.AuthType, api.Username, api.Secret)
-	rest.Timeout = 30 * time.Second
 	if err != nil {
-		return nil, err
+		return err
 	}
+	rest.Timeout = 30 * time.Second
+	rest.Cache = api.Cache
+	rest.Client = api.Client
 	err = rest.Login()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("requesting %s, %s", workingURL.String(), err)
 	}
+	// Track the URI seen so fn is only called for unique ones
+	m := make(map[string]bool)
+	var errFn error
+	err = walkEPrintIDPages(rest, workingURL.Path, func(val string) error {
+		if strings.HasSuffix(val, ".xml") == true {
+			uri := "/" + path.Join("rest", "eprint", val)
+			if _, hasID := m[uri]; hasID == false {
+				m[uri] = true
+				errFn = fn(uri)
+				return errFn
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errFn {
+		return fmt.Errorf("requested %s, %s", workingURL.String(), err)
+	}
+	return err
+}
 
+// walkIDSearch lists the eprint ids with api.IDSearch calling fn with
+// the URI of each unique id, see WalkEPrintsURI.
+func (api *EPrintsAPI) walkIDSearch(fn func(string) error) error {
+	u, err := url.Parse(api.IDSearch)
 	if err != nil {
-		return nil, fmt.Errorf("requesting %s, %s", workingURL.String(), err)
+		return fmt.Errorf("id search %q, %s", api.IDSearch, err)
 	}
-	content, err := rest.Request("GET", workingURL.Path, map[string]string{})
+	payload := map[string]string{}
+	for key, values := range u.Query() {
+		if len(values) > 0 {
+			payload[key] = values[0]
+		}
+	}
+	rest, err := rc.New(api.URL.String(), api.AuthType, api.Username, api.Secret)
 	if err != nil {
-		return nil, fmt.Errorf("requested %s, %s", workingURL.String(), err)
+		return err
 	}
-	eIDs := new(ePrintIDs)
-	err = xml.Unmarshal(content, &eIDs)
+	rest.Timeout = 30 * time.Second
+	rest.Client = api.Client
+	body, err := rest.Stream("GET", path.Join(api.URL.Path, u.Path), payload)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("listing eprint ids, %s", err)
 	}
-	// Build a list of Unique IDs in a map, then convert unique querys to results array
+	defer body.Close()
 	m := make(map[string]bool)
-	for _, val := range eIDs.IDs {
-		if strings.HasSuffix(val, ".xml") == true {
-			uri := "/" + path.Join("rest", "eprint", val)
-			if _, hasID := m[uri]; hasID == false {
-				// Save the new ID found
-				m[uri] = true
-				// Only store Unique IDs in result
-				results = append(results, uri)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if _, err := strconv.Atoi(id); err != nil {
+			//NOTE: skip blank lines and anything that isn't an id
+			continue
+		}
+		uri := "/" + path.Join("rest", "eprint", id+".xml")
+		if _, hasID := m[uri]; hasID == false {
+			m[uri] = true
+			if err := fn(uri); err != nil {
+				return err
 			}
 		}
 	}
-	return results, nil
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("listing eprint ids, %s", err)
+	}
+	return nil
 }
 
 // ListModifiedEPrintsURI return a list of modifed EPrint URI (eprint_ids) in start and end times
@@ -237,10 +335,12 @@ func (api *EPrintsAPI) ListModifiedEPrintsURI(start, end time.Time, verbose bool
 	}
 
 	rest, err := rc.New(api.URL.String(), api.AuthType, api.Username, api.Secret)
-	rest.Timeout = 30 * time.Second
 	if err != nil {
 		return nil, err
 	}
+	rest.Timeout = 30 * time.Second
+	rest.Cache = api.Cache
+	rest.Client = api.Client
 	err = rest.Login()
 	if err != nil {
 		return nil, err
@@ -283,6 +383,30 @@ func (api *EPrintsAPI) ListModifiedEPrintsURI(start, end time.Time, verbose bool
 	return results, nil
 }
 
+// archiveName returns the filename in api.XMLArchive for uri
+// using the last element of the uri (e.g. 1234.xml)
+func (api *EPrintsAPI) archiveName(uri string) string {
+	fName := path.Base(uri)
+	if path.Ext(fName) != ".xml" {
+		fName = fName + ".xml"
+	}
+	return filepath.Join(api.XMLArchive, fName)
+}
+
+// archiveXML saves the raw EPrint XML for uri in api.XMLArchive
+func (api *EPrintsAPI) archiveXML(uri string, src []byte) error {
+	if _, err := os.Stat(api.XMLArchive); os.IsNotExist(err) {
+		if err := os.MkdirAll(api.XMLArchive, 0775); err != nil {
+			return fmt.Errorf("can't create %s, %s", api.XMLArchive, err)
+		}
+	}
+	fName := api.archiveName(uri)
+	if err := ioutil.WriteFile(fName, src, 0664); err != nil {
+		return fmt.Errorf("can't archive %s to %s, %s", uri, fName, err)
+	}
+	return nil
+}
+
 // GetEPrint retrieves an EPrint record via REST API
 // Returns a EPrint structure, the raw XML and an error value.
 func (api *EPrintsAPI) GetEPrint(uri string) (*EPrint, []byte, error) {
@@ -299,25 +423,46 @@ func (api *EPrintsAPI) GetEPrint(uri string) (*EPrint, []byte, error) {
 
 	// Switch to use Rest Client Wrapper
 	rest, err := rc.New(workingURL.String(), api.AuthType, api.Username, api.Secret)
-	rest.Timeout = 30 * time.Second
 	if err != nil {
 		return nil, nil, fmt.Errorf("requesting %s, %s", workingURL.String(), err)
 	}
+	rest.Timeout = 30 * time.Second
+	rest.Cache = api.Cache
+	rest.Client = api.Client
+	rest.Validators = api.Validators
 	content, err := rest.Request("GET", workingURL.Path, map[string]string{})
-	if err != nil {
+	if err == rc.ErrNotModified && api.XMLArchive != "" {
+		content, err = ioutil.ReadFile(api.archiveName(uri))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if err != nil {
 		return nil, nil, err
+	} else if api.XMLArchive != "" {
+		if err := api.archiveXML(uri, content); err != nil {
+			return nil, content, err
+		}
 	}
 
 	eprints := new(EPrints)
-	err = xml.Unmarshal(content, &eprints)
+	err = DecodeXML(content, &eprints)
 	if err != nil {
 		return nil, content, err
 	}
 	if len(eprints.EPrint) == 1 {
+		if len(api.CustomFieldSelectors) > 0 {
+			if err := eprints.ApplyCustomFields(content, api.CustomFieldSelectors); err != nil {
+				return nil, content, err
+			}
+		}
 		if api.SuppressSuggestions {
 			eprints.EPrint[0].Suggestions = ""
 		}
-		if eprints.EPrint[0].EPrintStatus == "deletion" || eprints.EPrint[0].EPrintStatus == "inbox" || eprints.EPrint[0].EPrintStatus == "buffer" {
+		includeStatus := api.IncludeStatus
+		if len(includeStatus) == 0 {
+			includeStatus = DefaultEPrintStatus
+		}
+		if eprints.EPrint[0].HasStatus(includeStatus...) == false {
 			return eprints.EPrint[0], content, fmt.Errorf("WARNING status %s, %s", eprints.EPrint[0].ID, eprints.EPrint[0].EPrintStatus)
 		}
 		return eprints.EPrint[0], content, nil
@@ -328,6 +473,159 @@ func (api *EPrintsAPI) GetEPrint(uri string) (*EPrint, []byte, error) {
 	return nil, content, fmt.Errorf("Expected an eprint for %s", uri)
 }
 
+// eprintURI returns uri as a REST API path, a bare id like 1234
+// becomes /rest/eprint/1234.xml
+func eprintURI(uri string) string {
+	if strings.Contains(uri, "/") == false {
+		return "/" + path.Join("rest", "eprint", strings.TrimSuffix(uri, ".xml")+".xml")
+	}
+	return uri
+}
+
+// EPrintResult holds the outcome of retrieving one EPrint record in a
+// batch, see GetEPrintsBatch()
+type EPrintResult struct {
+	URI    string
+	EPrint *EPrint
+	XML    []byte
+	Err    error
+}
+
+// GetEPrintsBatch retrieves the EPrint records for uris (e.g.
+// /rest/eprint/1234.xml, a bare id like 1234 is also accepted) using
+// workers concurrent requests over a shared keep-alive connection pool.
+// Results are sent on the returned channel as they complete, in no
+// particular order, and the channel is closed when all are done or
+// ctx is cancelled. Cancel ctx if you stop reading results early.
+func (api *EPrintsAPI) GetEPrintsBatch(ctx context.Context, uris []string, workers int) <-chan *EPrintResult {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, uri := range uris {
+			select {
+			case ch <- uri:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return api.StreamEPrintsBatch(ctx, ch, workers)
+}
+
+// StreamEPrintsBatch works like GetEPrintsBatch but reads the uris
+// from a channel so they can be retrieved while the list is still
+// being read, e.g. from WalkEPrintsURI. Close uris when done, a sender
+// should also stop when ctx is cancelled.
+func (api *EPrintsAPI) StreamEPrintsBatch(ctx context.Context, uris <-chan string, workers int) <-chan *EPrintResult {
+	if workers < 1 {
+		workers = 1
+	}
+	batch := *api
+	if batch.Client == nil {
+		transport := &http.Transport{}
+		if t, ok := http.DefaultTransport.(*http.Transport); ok == true {
+			transport = t.Clone()
+		}
+		transport.MaxIdleConnsPerHost = workers
+		batch.Client = &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		}
+	}
+	results := make(chan *EPrintResult, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var (
+					uri string
+					ok  bool
+				)
+				select {
+				case uri, ok = <-uris:
+					if ok == false {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+				uri = eprintURI(uri)
+				eprint, src, err := batch.GetEPrint(uri)
+				select {
+				case results <- &EPrintResult{URI: uri, EPrint: eprint, XML: src, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// fieldContentType returns the content type for an EPrint field
+// sub-resource based on its extension (e.g. title.txt, creators.xml)
+func fieldContentType(field string) (string, error) {
+	switch path.Ext(field) {
+	case ".txt":
+		return "text/plain; charset=utf-8", nil
+	case ".xml":
+		return "text/xml; charset=utf-8", nil
+	default:
+		return "", fmt.Errorf("field %q should end in .txt or .xml", field)
+	}
+}
+
+// restClient returns a REST client for api
+func (api *EPrintsAPI) restClient() (*rc.RestAPI, error) {
+	rest, err := rc.New(api.URL.String(), api.AuthType, api.Username, api.Secret)
+	if err != nil {
+		return nil, err
+	}
+	rest.Timeout = 30 * time.Second
+	rest.Client = api.Client
+	return rest, nil
+}
+
+// GetField retrieves a single field of an EPrint record, uri is the
+// record (e.g. /rest/eprint/1234.xml or 1234) and field the field
+// sub-resource (e.g. title.txt for the text value, creators.xml for
+// the XML).
+func (api *EPrintsAPI) GetField(uri, field string) ([]byte, error) {
+	if _, err := fieldContentType(field); err != nil {
+		return nil, err
+	}
+	rest, err := api.restClient()
+	if err != nil {
+		return nil, err
+	}
+	p := path.Join(api.URL.Path, strings.TrimSuffix(eprintURI(uri), ".xml"), field)
+	return rest.Request("GET", p, map[string]string{})
+}
+
+// PutField replaces a single field of an EPrint record with src so a
+// small correction doesn't require sending the whole record, e.g.
+// PutField("1234", "title.txt", []byte("A corrected title")). The
+// content type is set from the field's extension.
+func (api *EPrintsAPI) PutField(uri, field string, src []byte) error {
+	contentType, err := fieldContentType(field)
+	if err != nil {
+		return err
+	}
+	rest, err := api.restClient()
+	if err != nil {
+		return err
+	}
+	p := path.Join(api.URL.Path, strings.TrimSuffix(eprintURI(uri), ".xml"), field)
+	_, err = rest.Send("PUT", p, contentType, bytes.NewReader(src))
+	return err
+}
+
 func (record *EPrint) PubDate() string {
 	if record.DateType == "published" {
 		return record.Date
@@ -1,5 +1,5 @@
 # EPrints3x API support
-from .eprints import harvest_init, harvest, harvest_keys, harvest_record, skip_and_prune
+from .eprints import harvest_init, harvest, harvest_keys, harvest_record, harvest_tombstones, make_tombstone, is_tombstone, skip_and_prune
 from .s3_publisher import s3_publish
 
 # EPrint Views
@@ -7,7 +7,10 @@ from .views import Views
 from .aggregator import Aggregator, slugify
 from .subjects import Subjects
 from .users import Users
-from .normalize import normalize_object, slugify, get_value, get_date_year, get_eprint_id, get_object_type, has_creator_ids, make_label, get_sort_name, get_sort_year, get_sort_subject, get_sort_publication, get_sort_collection, get_sort_event, get_lastmod_date, get_sort_lastmod, get_sort_issn, get_title, has_groups, get_sort_place_of_pub
+from .slugs import Slugs
+from .people import People
+from .groups import Groups
+from .normalize import normalize_object, slugify, get_value, get_date_year, get_eprint_id, get_object_type, has_creator_ids, make_label, get_sort_name, get_sort_year, get_sort_subject, get_sort_publication, get_sort_collection, get_sort_event, get_lastmod_date, get_sort_lastmod, get_sort_issn, get_title, has_groups, get_groups, get_sort_place_of_pub, get_initial, get_sort_title
 from .config import Configuration
 from .frames import make_frame_date_title
 from .logger import Logger
@@ -4,20 +4,48 @@ import json
 
 from datetime import date, timedelta
 
-from .normalize import slugify, get_date_year, get_eprint_id, get_object_type, has_creator_ids, has_editor_ids, has_contributor_ids, make_label, get_sort_name, get_sort_year, get_sort_subject, get_sort_publication, get_sort_collection, get_sort_event, get_lastmod_date, get_sort_lastmod, get_sort_issn, get_sort_corp_creator, get_sort_place_of_pub
+from .slugs import Slugs
+from .normalize import slugify, get_date_year, get_eprint_id, get_object_type, has_creator_ids, has_editor_ids, has_contributor_ids, make_label, get_sort_name, get_sort_year, get_sort_subject, get_sort_subject_path, get_sort_publication, get_sort_collection, get_sort_event, get_lastmod_date, get_sort_lastmod, get_sort_issn, get_sort_corp_creator, get_sort_place_of_pub, get_groups, get_sort_group, get_initial, get_sort_title
 
 
+#
+# subject_label returns the label of a subject, falling back to
+# its id when the subjects file doesn't have it.
+#
+def subject_label(subject_map, subj):
+    if subject_map.has_subject(subj):
+        return subject_map.get_subject(subj)
+    return subj
+
 class Aggregator:
     """This class models the various Eprint aggregations used across Caltech Library repositories"""
-    def __init__(self, c_name, objs):
+    def __init__(self, c_name, objs, slugs = None, people = None, groups = None):
         self.c_name = c_name
         self.objs = objs
+        if slugs == None:
+            slugs = Slugs()
+        self.slugs = slugs
+        self.people = people
+        self.groups = groups
+
+    def slug(self, view, label):
+        return self.slugs.get_slug(view, label)
+
+    def person(self, person_id, name):
+        # NOTE: the people authority (if any) merges the ids and name
+        # variants of a person into one key and label.
+        if self.people != None:
+            person = self.people.get_person(person_id, name)
+            if person != None:
+                return person['key'], person['label']
+        return person_id, name
 
     def aggregate_creator(self):
         # now build our people list and create a people, eprint_id, title list
         people = {}
         for obj in self.objs:
-            if has_creator_ids(obj):
+            if 'creators' in obj:
+                seen = {}
                 # For each author add a reference to object
                 for creator in obj['creators']:
                     creator_id = ''
@@ -26,7 +54,9 @@ class Aggregator:
                     if 'creator_id' in creator:
                         creator_id = creator['creator_id']
                     creator_name = creator['display_name']
-                    if creator_id != '':
+                    creator_id, creator_name = self.person(creator_id, creator_name)
+                    if (creator_id != '') and not (creator_id in seen):
+                        seen[creator_id] = True
                         if not creator_id in people:
                             people[creator_id] = { 
                                 'key': creator_id,
@@ -49,7 +79,8 @@ class Aggregator:
         # now build our people list based on editors.items
         people = {}
         for obj in self.objs:
-            if has_editor_ids(obj):
+            if 'editors' in obj:
+                seen = {}
                 # For each author add a reference to object
                 for editor in obj['editors']:
                     editor_id = ''
@@ -58,7 +89,9 @@ class Aggregator:
                     if 'editor_id' in editor:
                         editor_id = editor['editor_id']
                     editor_name = editor['display_name']
-                    if editor_id != '':
+                    editor_id, editor_name = self.person(editor_id, editor_name)
+                    if (editor_id != '') and not (editor_id in seen):
+                        seen[editor_id] = True
                         if not editor_id in people:
                             people[editor_id] = { 
                                 'key': editor_id,
@@ -81,7 +114,8 @@ class Aggregator:
         # now build our people list based on contributors.items
         people = {}
         for obj in self.objs:
-            if has_contributor_ids(obj):
+            if 'contributors' in obj:
+                seen = {}
                 # For each author add a reference to object
                 for contributor in obj['contributors']:
                     contributor_id = ''
@@ -90,7 +124,9 @@ class Aggregator:
                     if 'contributor_id' in contributor:
                         contributor_id = contributor['contributor_id']
                     contributor_name = contributor['display_name']
-                    if contributor_id != '':
+                    contributor_id, contributor_name = self.person(contributor_id, contributor_name)
+                    if (contributor_id != '') and not (contributor_id in seen):
+                        seen[contributor_id] = True
                         if not contributor_id in people:
                             people[contributor_id] = { 
                                 'key': contributor_id,
@@ -112,6 +148,8 @@ class Aggregator:
     def aggregate_by_view_name(self, name, subject_map):
         if name == 'person-az':
             return self.aggregate_person_az()
+        elif name == 'title-az':
+            return self.aggregate_title_az()
         elif name == 'person':
             return self.aggregate_person()
         elif name == 'author':
@@ -132,6 +170,8 @@ class Aggregator:
             return self.aggregate_issuing_body()
         elif name == 'issn':
             return self.aggregate_issn()
+        elif name == 'group':
+            return self.aggregate_group()
         elif name == 'collection':
             return self.aggregate_collection()
         elif name == 'event':
@@ -149,7 +189,34 @@ class Aggregator:
             return None
 
     def aggregate_person_az(self):
-        return self.aggregate_creator()
+        # NOTE: people are listed under the initial of their sort
+        # name, genviews.py builds the letter navigation from it.
+        people_list = self.aggregate_creator()
+        for person in people_list:
+            person['letter'] = get_initial(get_sort_name(person))
+        people_list.sort(key = lambda x: (x['letter'], slugify(get_sort_name(x))))
+        return people_list
+
+    def aggregate_title_az(self):
+        titles = {}
+        for obj in self.objs:
+            letter = get_initial(get_sort_title(obj))
+            if not letter in titles:
+                titles[letter] = {
+                    'key': letter.lower(),
+                    'label': letter,
+                    'letter': letter,
+                    'count': 0,
+                    'objects': []
+                }
+            titles[letter]['count'] += 1
+            titles[letter]['objects'].append(obj)
+        title_list = []
+        for key in titles:
+            titles[key]['objects'].sort(key = get_sort_title)
+            title_list.append(titles[key])
+        title_list.sort(key = lambda x: x['letter'])
+        return title_list
     
     def aggregate_person(self):
         return self.aggregate_creator()
@@ -187,7 +254,7 @@ class Aggregator:
             year = get_date_year(obj)
             if ('publication' in obj):
                 publication = obj['publication']
-                key = slugify(publication)
+                key = self.slug('publication', publication)
                 if not publication in publications:
                     publications[publication] = { 
                         'key': key,
@@ -214,7 +281,7 @@ class Aggregator:
                     if 'id' in item:
                         key = str(item['id'])
                     else:
-                        key = slugify(corp_creator)
+                        key = self.slug('corp_creators', corp_creator)
                     if not key in corp_creators:
                         corp_creators[key] = { 
                             'key': key,
@@ -234,6 +301,36 @@ class Aggregator:
     def aggregate_issuing_body(self):
         return self.aggregate_corp_creators()
 
+    def aggregate_group(self):
+        groups = {}
+        for obj in self.objs:
+            year = get_date_year(obj)
+            seen = {}
+            for value in get_groups(obj):
+                # NOTE: the group alias map (if any) consolidates
+                # the variant spellings of a group's name.
+                group = value.strip()
+                if self.groups != None:
+                    group = self.groups.get_group(group)
+                if (group == '') or (group in seen):
+                    continue
+                seen[group] = True
+                if not group in groups:
+                    groups[group] = {
+                        'key': self.slug('group', group),
+                        'label': group,
+                        'count': 0,
+                        'year': year,
+                        'objects': []
+                    }
+                groups[group]['count'] += 1
+                groups[group]['objects'].append(obj)
+        group_list = []
+        for key in groups:
+            group_list.append(groups[key])
+        group_list.sort(key = get_sort_group)
+        return group_list
+
     def aggregate_issn(self):
         issns = {}
         issn = ''
@@ -266,7 +363,7 @@ class Aggregator:
             year = get_date_year(obj)
             if ('place_of_pub' in obj):
                 place_of_pub = obj['place_of_pub'].strip()
-                key = slugify(place_of_pub)
+                key = self.slug('place_of_pub', place_of_pub)
                 if not place_of_pub in place_of_pubs:
                     place_of_pubs[place_of_pub] = { 
                         'key': key,
@@ -292,7 +389,7 @@ class Aggregator:
             year = get_date_year(obj)
             if ('collection' in obj):
                 collection = obj['collection']
-                key = slugify(collection)
+                key = self.slug('collection', collection)
                 if not collection in collections:
                     collections[collection] = { 
                         'key': key,
@@ -326,7 +423,7 @@ class Aggregator:
                 event_dates = obj['event_dates']
             if event_title != '':
                 if not event_title in events:
-                    key = slugify(event_title)
+                    key = self.slug('event', event_title)
                     events[event_title] = { 
                         'key': key,
                         'label': event_title,
@@ -350,16 +447,32 @@ class Aggregator:
             year = get_date_year(obj)
     
             if ('subjects' in obj):
-                for subj in obj['subjects']['items']:
-                    subject_name = subject_map.get_subject(subj)
-                    if subject_name != None:
+                # NOTE: a record is also listed under the ancestors
+                # of its subjects, once per subject.
+                seen = {}
+                for item in obj['subjects']['items']:
+                    for subj in subject_map.get_ancestors(item) + [ item ]:
+                        if subj in seen:
+                            continue
+                        seen[subj] = True
                         if not subj in subjects:
+                            ancestors = subject_map.get_ancestors(subj)
+                            path = []
+                            for ancestor in ancestors:
+                                path.append(subject_label(subject_map, ancestor))
+                            breadcrumb = ''
+                            if len(path) > 0:
+                                breadcrumb = ': '.join(path) + ': '
+                            subject_name = subject_label(subject_map, subj)
                             subjects[subj] = { 
                                 'key': subj,
                                 'label': subject_name,
                                 'count': 0,
                                 'subject_id': subj, 
                                 'subject_name': subject_name,
+                                'depth': len(ancestors),
+                                'breadcrumb': breadcrumb,
+                                'sort_path': path + [ subject_name ],
                                 'objects': [] 
                             }
                         subjects[subj]['count'] += 1
@@ -367,7 +480,10 @@ class Aggregator:
         subject_list= []
         for key in subjects:
             subject_list.append(subjects[key])
-        subject_list.sort(key = get_sort_subject)
+        # NOTE: sorting on the path lists each subject after its parent
+        subject_list.sort(key = get_sort_subject_path)
+        for subject in subject_list:
+            del subject['sort_path']
         return subject_list
 
     def aggregate_ids(self):
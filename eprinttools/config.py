@@ -20,6 +20,9 @@ class Configuration:
         self.views = ''
         self.subjects = ''
         self.users = ''
+        self.slugs = ''
+        self.people = ''
+        self.groups = ''
         self.organization = ''
         self.site_title = ''
         self.site_welcome = ''
@@ -98,6 +101,15 @@ class Configuration:
                     if not os.path.exists(self.users):
                         print(f'''Can't find users {self.users} listed in {f_name}''')
                         ok = False
+                if 'slugs' in data:
+                    self.slugs = data['slugs']
+                if 'people' in data:
+                    self.people = data['people']
+                if 'groups' in data:
+                    self.groups = data['groups']
+                    if not os.path.exists(self.groups):
+                        print(f'''Can't find groups {self.groups} listed in {f_name}''')
+                        ok = False
                 if 'organization' in data:
                     self.organization = data['organization']
                 if 'site_welcome' in data:
@@ -203,6 +215,19 @@ class Configuration:
             elif not os.path.exists(self.subjects):
                 print(f'subjects {self.subjects} does not exist.')
                 ok = False
+        if ('slugs' in settings) and (self.slugs == ''):
+            print(f'slugs not set in {f_name}')
+            ok = False
+        if ('people' in settings) and (self.people == ''):
+            print(f'people not set in {f_name}')
+            ok = False
+        if ('groups' in settings):
+            if (self.groups == ''):
+                print(f'groups not set in {f_name}')
+                ok = False
+            elif not os.path.exists(self.groups):
+                print(f'groups {self.groups} does not exist.')
+                ok = False
         if ('views' in settings):
             if (self.views == ''):
                 print(f'views not set in {f_name}')
@@ -264,6 +289,12 @@ class Configuration:
             o['subjects'] = self.subjects
         if self.views != '':
             o['views'] = self.views
+        if self.slugs != '':
+            o['slugs'] = self.slugs
+        if self.people != '':
+            o['people'] = self.people
+        if self.groups != '':
+            o['groups'] = self.groups
         if self.organization != '':
             o['organization'] = self.organization
         if self.site_welcome != '':
@@ -83,10 +83,39 @@ def harvest_keys():
     return keys
 
 
+#
+# make_tombstone returns the record stored in place of an EPrint
+# which has been withdrawn or removed from the repository. It
+# keeps just enough metadata to render a "withdrawn" page.
+#
+def make_tombstone(key, obj = None):
+    tombstone = {
+        'eprint_id': key,
+        'eprint_status': 'deletion',
+        'tombstone': True
+    }
+    if obj != None:
+        for field in [ 'eprint_id', 'title', 'datestamp', 'lastmod' ]:
+            if field in obj:
+                tombstone[field] = obj[field]
+        if ('eprint_status' in obj) and (obj['eprint_status'] in skip_and_prune):
+            tombstone['eprint_status'] = obj['eprint_status']
+    return tombstone
+
+#
+# is_tombstone returns True if obj is a tombstone record
+#
+def is_tombstone(obj):
+    if ('tombstone' in obj) and obj['tombstone']:
+        return True
+    return False
+
 #
 # harvest_record fetches a single EPrints record including
 # it's EPrintsXML as well as related objects. Store them
-# in a dataset collection as attachments.
+# in a dataset collection as attachments. Previously harvested
+# records whose eprint_status is in skip_and_prune are replaced
+# by a tombstone.
 #
 def harvest_record(key, verbose = False):
     global base_url, c_name
@@ -115,11 +144,12 @@ def harvest_record(key, verbose = False):
         if status in skip_and_prune:
             if verbose:
                 print(f'''
-WARNING: Removing {key} from {c_name}, reason {status}''')
-            ok = dataset.delete(c_name, key)
+WARNING: Replacing {key} in {c_name} with a tombstone, reason {status}''')
+            tombstone = make_tombstone(key, obj)
+            ok = dataset.update(c_name, key, tombstone)
             if not ok:
                 return None, dataset.error_message()
-            return None, ''
+            return tombstone, ''
         ok = dataset.update(c_name, key, obj)
         if not ok:
             return None, dataset.error_message()
@@ -135,6 +165,37 @@ WARNING: Skipping {key} from {c_name}, reason {status}''')
     return obj, ''
 
 
+#
+# harvest_tombstones compares the keys listed by the repository
+# with those in the dataset collection. Records EPrints no longer
+# lists are replaced by a tombstone. Returns the number of
+# tombstones written and an error message.
+#
+def harvest_tombstones(keys, verbose = False):
+    global c_name
+    repo_keys = {}
+    for key in keys:
+        repo_keys[str(key)] = True
+    cnt = 0
+    for key in dataset.keys(c_name):
+        key = str(key)
+        if key in repo_keys:
+            continue
+        obj, err = dataset.read(c_name, key)
+        if err != '':
+            return cnt, err
+        if is_tombstone(obj):
+            continue
+        if verbose:
+            print(f'''
+WARNING: Replacing {key} in {c_name} with a tombstone, no longer in repository''')
+        ok = dataset.update(c_name, key, make_tombstone(key, obj))
+        if not ok:
+            return cnt, dataset.error_message()
+        cnt += 1
+    return cnt, ''
+
+
 #
 # harvest_eprintxml retrieves and attaches an EPrintXML document
 # for the requested record.
@@ -271,6 +332,7 @@ def harvest(keys = [], start_id = 0, save_exported_keys = '', number_of_days = N
     tot = len(keys)
     e_cnt = 0
     pruned = 0
+    tombstones = 0
     n = 0
     bar = progressbar.ProgressBar(
             max_value = tot,
@@ -295,6 +357,12 @@ WARNING: harvest record {key}, {err}''', file = sys.stderr)
             pruned += 1
             bar.update(i)
             continue
+        # NOTE: A tombstone replaced a withdrawn record, there is
+        # no EPrintXML or documents to harvest for it.
+        if is_tombstone(obj):
+            tombstones += 1
+            bar.update(i)
+            continue
         err = harvest_eprintxml(key)
         if err != '':
             print(f'''
@@ -315,7 +383,7 @@ WARNING harvest documents {key}, {err}''', file = sys.stderr)
         n += 1
         bar.update(i)
     bar.finish()
-    print(f'harvested {n}/{tot}, skipped/pruned {pruned} from {repo_name}, {e_cnt} warnings')
+    print(f'harvested {n}/{tot}, skipped/pruned {pruned}, tombstones {tombstones} from {repo_name}, {e_cnt} warnings')
     if save_exported_keys != '':
         print(f'saving exported keys to {save_exported_keys}')
         with open(save_exported_keys, 'w') as f:
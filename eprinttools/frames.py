@@ -50,6 +50,7 @@ def make_frame_date_title(cfg):
         '.editors', 
         '.contributors', 
         '.corp_creators', 
+        '.local_group', 
         '.subjects', 
         '.type', 
         '.official_url', 
@@ -64,7 +65,8 @@ def make_frame_date_title(cfg):
         '.series' 
         '.pagerange', 
         '.userid' , 
-        '.lastmod'
+        '.lastmod',
+        '.tombstone'
     ]
     labels = []
     for label in dot_paths:
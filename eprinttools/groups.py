@@ -0,0 +1,53 @@
+import os
+import sys
+import json
+
+from .normalize import slugify
+
+class Groups:
+    """Groups maps the variant spellings of local group names to one group name and keeps track of values which had no mapping"""
+
+    def __init__(self):
+        self.groups = {}
+        self.aliases = {}
+        self.unmatched = {}
+
+    def load_groups(self, f_name):
+        """Load a group alias file, a JSON object of group names each with a list of aliases"""
+        with open(f_name) as f:
+            src = f.read()
+            try:
+                self.groups = json.loads(src)
+            except Exception as err:
+                print(f'''Failed to parse JSON file {f_name}, {err}''')
+                sys.exit(1)
+        self.aliases = {}
+        for group in self.groups:
+            self.aliases[slugify(group)] = group
+            for alias in self.groups[group]:
+                self.aliases[slugify(alias)] = group
+
+    def has_group(self, value):
+        if slugify(value) in self.aliases:
+            return True
+        return False
+
+    def get_group(self, value):
+        """Return the group name value is an alias of, otherwise value which is counted as unmatched"""
+        value = value.strip()
+        key = slugify(value)
+        if key in self.aliases:
+            return self.aliases[key]
+        if not value in self.unmatched:
+            self.unmatched[value] = 0
+        self.unmatched[value] += 1
+        return value
+
+    def unmatched_list(self):
+        """Return the unmatched values and their counts, most frequent first"""
+        l = []
+        for value in self.unmatched:
+            l.append({ 'value': value, 'count': self.unmatched[value] })
+        l.sort(key = lambda x: x['value'])
+        l.sort(key = lambda x: x['count'], reverse = True)
+        return l
@@ -1,10 +1,31 @@
+import re
+import unicodedata
+
 #
 # utility methods
 #
 
 
+#
+# slugify turns a label into a human readable path element,
+# e.g. "Física Teórica / GALCIT" becomes "fisica-teorica-galcit".
+# Diacritics are folded to ASCII, letters lower cased and other
+# runs of characters collapsed into a single dash.
+#
 def slugify(s):
-    return s.replace(' ', '_').replace('/','_')
+    s = unicodedata.normalize('NFKD', f'{s}')
+    s = s.encode('ascii', 'ignore').decode('ascii').lower()
+    return re.sub(r'[^a-z0-9]+', '-', s).strip('-')
+
+#
+# get_initial returns the letter a label is listed under in an A-Z
+# view, labels which don't start with a letter are listed under 0-9.
+#
+def get_initial(s):
+    s = slugify(s)
+    if (len(s) > 0) and s[0].isalpha():
+        return s[0].upper()
+    return '0-9'
 
 def get_value(obj, key):
     if key in obj:
@@ -31,6 +52,16 @@ def has_groups(obj):
         return True
     return False
 
+def get_groups(obj):
+    if has_groups(obj):
+        groups = obj['local_group']
+        if isinstance(groups, dict) and ('items' in groups):
+            groups = groups['items']
+        if isinstance(groups, str):
+            return [ groups ]
+        return groups
+    return []
+
 def get_object_type(obj):
     if 'type' in obj:
         return f'{obj["type"]}'
@@ -78,6 +109,18 @@ def get_sort_subject(o):
         return o['subject_name']
     return ''
 
+def get_sort_subject_path(o):
+    if 'sort_path' in o:
+        return o['sort_path']
+    return []
+
+def get_sort_title(o):
+    title = slugify(get_title(o))
+    for article in [ 'the-', 'an-', 'a-' ]:
+        if title.startswith(article):
+            return title[len(article):]
+    return title
+
 def get_sort_publication(o):
     if ('publication' in o) and ('item' in publication['publication']):
         return o['publication']['item']
@@ -113,6 +156,11 @@ def get_sort_issn(o):
         return o['issn']
     return ''
 
+def get_sort_group(o):
+    if ('label' in o):
+        return o['label']
+    return ''
+
 def get_sort_corp_creator(o):
     if ('name' in o):
         return o['name']
@@ -0,0 +1,121 @@
+import os
+import sys
+import json
+
+from .normalize import slugify
+
+class People:
+    """People is an authority list merging the creator ids and name variants of a person into one entry used for person pages"""
+
+    def __init__(self):
+        self.people = {}
+        self.ids = {}
+        self.names = {}
+
+    def load_people(self, f_name):
+        """Load a people authority file, a JSON list of objects with key, label, ids and names"""
+        objects = []
+        if not os.path.exists(f_name):
+            return
+        with open(f_name) as f:
+            src = f.read()
+            try:
+                objects = json.loads(src)
+            except Exception as err:
+                print(f'''Failed to parse JSON file {f_name}, {err}''')
+                sys.exit(1)
+        for obj in objects:
+            if not 'key' in obj:
+                continue
+            key = obj['key']
+            label = key
+            if 'label' in obj:
+                label = obj['label']
+            self.add_person(key, label)
+            if 'ids' in obj:
+                for creator_id in obj['ids']:
+                    self.add_id(key, creator_id)
+            if 'names' in obj:
+                for name in obj['names']:
+                    self.add_name(key, name)
+
+    def save_people(self, f_name):
+        with open(f_name, 'w') as f:
+            src = json.dumps(self.person_list(), indent = 4)
+            f.write(src)
+
+    def has_person(self, key):
+        if key in self.people:
+            return True
+        return False
+
+    def add_person(self, key, label):
+        if not key in self.people:
+            self.people[key] = { 'key': key, 'label': label, 'ids': [], 'names': [] }
+        else:
+            self.people[key]['label'] = label
+        return self.people[key]
+
+    def add_id(self, key, creator_id):
+        person = self.people[key]
+        self.remove_id(creator_id)
+        person['ids'].append(creator_id)
+        self.ids[creator_id] = key
+
+    def add_name(self, key, name):
+        person = self.people[key]
+        self.remove_name(name)
+        person['names'].append(name)
+        self.names[slugify(name)] = key
+
+    def remove_id(self, creator_id):
+        """Remove creator_id from the person it was mapped to, returns that person's key"""
+        if not creator_id in self.ids:
+            return ''
+        key = self.ids[creator_id]
+        del self.ids[creator_id]
+        self.people[key]['ids'].remove(creator_id)
+        return key
+
+    def remove_name(self, name):
+        """Remove name from the person it was mapped to, returns that person's key"""
+        name_key = slugify(name)
+        if not name_key in self.names:
+            return ''
+        key = self.names[name_key]
+        del self.names[name_key]
+        for variant in self.people[key]['names']:
+            if slugify(variant) == name_key:
+                self.people[key]['names'].remove(variant)
+                break
+        return key
+
+    def merge(self, key, other_key):
+        """Merge the ids and names of other_key into key and remove other_key"""
+        other = self.people[other_key]
+        del self.people[other_key]
+        for creator_id in other['ids']:
+            del self.ids[creator_id]
+            self.add_id(key, creator_id)
+        for name in other['names']:
+            del self.names[slugify(name)]
+            self.add_name(key, name)
+
+    def get_person(self, creator_id, name):
+        """Return the person creator_id or name is mapped to, None if neither are"""
+        if (creator_id != '') and (creator_id in self.ids):
+            return self.people[self.ids[creator_id]]
+        name_key = slugify(name)
+        if (name_key != '') and (name_key in self.names):
+            return self.people[self.names[name_key]]
+        return None
+
+    def person_list(self):
+        keys = []
+        for key in self.people:
+            keys.append(key)
+        keys.sort()
+        l = []
+        for key in keys:
+            l.append(self.people[key])
+        return l
@@ -0,0 +1,54 @@
+import os
+import sys
+import json
+
+from .normalize import slugify
+
+class Slugs:
+    """Slugs remembers the path element assigned to each label of a view so labels which slugify to the same value get distinct paths and existing paths are kept between builds"""
+
+    def __init__(self):
+        self.slugs = {}
+        self.used = {}
+
+    def load_slugs(self, f_name):
+        """Load a slug map saved by save_slugs, a missing file is treated as an empty map"""
+        if not os.path.exists(f_name):
+            return
+        with open(f_name) as f:
+            src = f.read()
+            try:
+                self.slugs = json.loads(src)
+            except Exception as err:
+                print(f'''Failed to parse JSON file {f_name}, {err}''')
+                sys.exit(1)
+        self.used = {}
+
+    def save_slugs(self, f_name):
+        """Save the slug map so the next build assigns the same paths"""
+        with open(f_name, 'w') as f:
+            src = json.dumps(self.slugs, indent = 4, sort_keys = True)
+            f.write(src)
+
+    def get_slug(self, view, label):
+        """Return the slug of label in view, assigning a new one if label hasn't been seen"""
+        if not view in self.slugs:
+            self.slugs[view] = {}
+        labels = self.slugs[view]
+        if label in labels:
+            return labels[label]
+        if not view in self.used:
+            self.used[view] = set(labels.values())
+        used = self.used[view]
+        slug = slugify(label)
+        if slug == '':
+            slug = 'item'
+        # NOTE: a different label already has this slug, number
+        # this one so the paths don't collide.
+        candidate, i = slug, 1
+        while candidate in used:
+            i += 1
+            candidate = f'{slug}-{i}'
+        labels[label] = candidate
+        used.add(candidate)
+        return candidate
@@ -3,13 +3,14 @@ import sys
 import json
 
 class Subjects:
-    """Subjects models the Eprint subjects values into a flat list"""
+    """Subjects models the Eprint subjects values and the tree their parents describe"""
 
     def __init__ (self):
         self.subjects = {}
+        self.parents = {}
 
     def load_subjects(self, f_name):
-        """Load an Eprint subjects file, e.g. /eprint3-1/archives/REPO/cfg/subjects"""
+        """Load an Eprint subjects file, e.g. /eprint3-1/archives/REPO/cfg/subjects, lines are formatted ID:LABEL:PARENTS:DEPOSITABLE"""
         with open(f_name) as f:
             lines = f.readlines()
             for line in lines:
@@ -26,6 +27,13 @@ class Subjects:
                         label = parts[1].strip()
                         # add to self.subjects
                         self.subjects[key] = label
+                        # parents are a comma separated list of ids
+                        self.parents[key] = []
+                        if len(parts) > 2:
+                            for parent in parts[2].split(','):
+                                parent = parent.strip()
+                                if (parent != '') and (parent != 'ROOT'):
+                                    self.parents[key].append(parent)
 
         
     def has_subject(self, key):
@@ -43,3 +51,22 @@ class Subjects:
         for key in self.subjects:
             keys.append(key)
         return keys
+
+    def get_parents(self, key):
+        if key in self.parents:
+            return self.parents[key]
+        return []
+
+    def get_ancestors(self, key):
+        """Return the ids from the top of the tree down to key's parent, following the first parent of each subject"""
+        ancestors = []
+        seen = { key: True }
+        parents = self.get_parents(key)
+        while len(parents) > 0:
+            parent = parents[0]
+            if parent in seen:
+                break
+            seen[parent] = True
+            ancestors.insert(0, parent)
+            parents = self.get_parents(parent)
+        return ancestors
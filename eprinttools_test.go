@@ -19,7 +19,15 @@
 package eprinttools
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"strings"
 	"testing"
 	"time"
 )
@@ -57,3 +65,210 @@ func TestListEPrintsURI(t *testing.T) {
 		t.Errorf("Expected uris for list modified from %s to %s", start.String(), end.String())
 	}
 }
+
+func TestArchiveXML(t *testing.T) {
+	api, err := New("https://example.org", false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	api.XMLArchive = path.Join(t.TempDir(), "xml")
+	src := []byte(`<?xml version='1.0' encoding='utf-8'?><eprints></eprints>`)
+	if err := api.archiveXML("/rest/eprint/1234.xml", src); err != nil {
+		t.Errorf("archiveXML() returned an error, %s", err)
+		t.FailNow()
+	}
+	buf, err := ioutil.ReadFile(path.Join(api.XMLArchive, "1234.xml"))
+	if err != nil {
+		t.Errorf("expected archived XML, %s", err)
+		t.FailNow()
+	}
+	if bytes.Compare(buf, src) != 0 {
+		t.Errorf("expected %q, got %q", src, buf)
+	}
+}
+
+func TestGetEPrintsBatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(path.Base(r.URL.Path), ".xml")
+		if id == "404" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version='1.0' encoding='utf-8'?>
+<eprints><eprint id="%s"><eprintid>%s</eprintid><eprint_status>archive</eprint_status><title>Record %s</title></eprint></eprints>`, id, id, id)
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	uris := []string{"/rest/eprint/1.xml", "2", "3", "404"}
+	seen := map[string]bool{}
+	for result := range api.GetEPrintsBatch(context.Background(), uris, 2) {
+		seen[result.URI] = true
+		if result.URI == "/rest/eprint/404.xml" {
+			if result.Err == nil {
+				t.Errorf("expected an error for %s", result.URI)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("unexpected error for %s, %s", result.URI, result.Err)
+			continue
+		}
+		expected := "Record " + strings.TrimSuffix(path.Base(result.URI), ".xml")
+		if result.EPrint.Title != expected {
+			t.Errorf("expected %q, got %q", expected, result.EPrint.Title)
+		}
+	}
+	if len(seen) != len(uris) {
+		t.Errorf("expected %d results, got %d", len(uris), len(seen))
+	}
+}
+
+func TestGetPutField(t *testing.T) {
+	title := []byte("A title")
+	contentType := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/eprint/1234/title.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			w.Write(title)
+		case "PUT":
+			contentType = r.Header.Get("Content-Type")
+			title, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	src, err := api.GetField("/rest/eprint/1234.xml", "title.txt")
+	if err != nil {
+		t.Errorf("GetField() returned an error, %s", err)
+	} else if string(src) != "A title" {
+		t.Errorf("expected %q, got %q", "A title", src)
+	}
+	if err := api.PutField("1234", "title.txt", []byte("A corrected title")); err != nil {
+		t.Errorf("PutField() returned an error, %s", err)
+	}
+	if string(title) != "A corrected title" {
+		t.Errorf("expected title to be updated, got %q", title)
+	}
+	if contentType != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	if err := api.PutField("1234", "title", []byte("no extension")); err == nil {
+		t.Errorf("expected an error for a field without .txt or .xml")
+	}
+	if _, err := api.GetField("1234", "missing.txt"); err == nil {
+		t.Errorf("expected an error for a missing field")
+	}
+}
+
+func TestGetEPrintsBatchCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(path.Base(r.URL.Path), ".xml")
+		fmt.Fprintf(w, `<?xml version='1.0' encoding='utf-8'?>
+<eprints><eprint id="%s"><eprintid>%s</eprintid><eprint_status>archive</eprint_status></eprint></eprints>`, id, id)
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	uris := []string{}
+	for i := 1; i <= 100; i++ {
+		uris = append(uris, fmt.Sprintf("%d", i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results := api.GetEPrintsBatch(ctx, uris, 2)
+	<-results
+	cancel()
+	cnt := 1
+	for range results {
+		cnt++
+	}
+	if cnt >= len(uris) {
+		t.Errorf("expected the batch to stop early, got %d results", cnt)
+	}
+}
+
+func TestWalkEPrintsURIPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `<html><body><ul>
+<li><a href='1.xml'>1.xml</a>
+<li><a href='2.xml'>2.xml</a>
+</ul><a rel="next" href="?page=2">Next</a></body></html>`)
+		case "2":
+			fmt.Fprintf(w, `<html><body><ul>
+<li><a href='2.xml'>2.xml</a>
+<li><a href='3.xml'>3.xml</a>
+</ul><a rel="prev" href="/rest/eprint/">Previous</a>
+<a rel="next" href="/rest/eprint/?page=3">Next</a></body></html>`)
+		case "3":
+			fmt.Fprintf(w, `<html><body><ul>
+<li><a href='4.xml'>4.xml</a>
+</ul><a rel="prev" href="/rest/eprint/?page=2">Previous</a></body></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	uris, err := api.ListEPrintsURI()
+	if err != nil {
+		t.Errorf("ListEPrintsURI() returned an error, %s", err)
+		t.FailNow()
+	}
+	expected := []string{"/rest/eprint/1.xml", "/rest/eprint/2.xml", "/rest/eprint/3.xml", "/rest/eprint/4.xml"}
+	if strings.Join(uris, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %+v, got %+v", expected, uris)
+	}
+}
+
+func TestWalkEPrintsURISearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cgi/search/archive/advanced/export_Ids.txt" || r.URL.Query().Get("output") != "Ids" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "12\n\n7\n12\n")
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	api.IDSearch = DefaultIDSearch
+	uris, err := api.ListEPrintsURI()
+	if err != nil {
+		t.Errorf("ListEPrintsURI() returned an error, %s", err)
+		t.FailNow()
+	}
+	expected := []string{"/rest/eprint/12.xml", "/rest/eprint/7.xml"}
+	if strings.Join(uris, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %+v, got %+v", expected, uris)
+	}
+}
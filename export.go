@@ -0,0 +1,193 @@
+package eprinttools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Column describes a field exported by WriteCSV using the Table
+// Schema field vocabulary (https://specs.frictionlessdata.io/table-schema/)
+type Column struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// value extracts the column's value from an EPrint
+	value func(*EPrint) interface{}
+}
+
+// creatorNames returns the creators as "Family, Given" joined by "; "
+func creatorNames(e *EPrint) string {
+	names := []string{}
+	if e.Creators != nil {
+		for _, item := range e.Creators.Items {
+			if item.Name == nil {
+				continue
+			}
+			if item.Name.Family != "" {
+				names = append(names, strings.TrimSpace(fmt.Sprintf("%s, %s", item.Name.Family, item.Name.Given)))
+			} else if item.Name.Value != "" {
+				names = append(names, item.Name.Value)
+			}
+		}
+	}
+	return strings.Join(names, "; ")
+}
+
+// yearOf returns the year of the EPrint's date or nil if not known
+func yearOf(e *EPrint) interface{} {
+	if y, err := strconv.Atoi(e.citationYear()); err == nil {
+		return y
+	}
+	return nil
+}
+
+// licenseOf returns the EPrint's SPDX license identifier or nil if
+// not known
+func licenseOf(e *EPrint) interface{} {
+	if info, ok := e.License(); ok == true {
+		return info.SPDX
+	}
+	return nil
+}
+
+// CSVColumns are the core fields exported by WriteCSV, in order
+var CSVColumns = []*Column{
+	{"eprint_id", "integer", "EPrint record id", func(e *EPrint) interface{} { return e.EPrintID }},
+	{"eprint_status", "string", "", func(e *EPrint) interface{} { return e.EPrintStatus }},
+	{"type", "string", "EPrint item type (e.g. article, book_section)", func(e *EPrint) interface{} { return e.Type }},
+	{"title", "string", "", func(e *EPrint) interface{} { return e.Title }},
+	{"creators", "string", "creator names (family, given) separated by semicolons", func(e *EPrint) interface{} { return creatorNames(e) }},
+	{"date", "string", "date as YYYY, YYYY-MM or YYYY-MM-DD", func(e *EPrint) interface{} { return e.Date }},
+	{"date_type", "string", "", func(e *EPrint) interface{} { return e.DateType }},
+	{"year", "integer", "year from date", yearOf},
+	{"doi", "string", "DOI or DOI URL", func(e *EPrint) interface{} { return e.getDOI() }},
+	{"publication", "string", "", func(e *EPrint) interface{} { return e.Publication }},
+	{"volume", "string", "", func(e *EPrint) interface{} { return e.Volume }},
+	{"number", "string", "", func(e *EPrint) interface{} { return e.Number }},
+	{"pagerange", "string", "", func(e *EPrint) interface{} { return e.PageRange }},
+	{"publisher", "string", "", func(e *EPrint) interface{} { return e.Publisher }},
+	{"issn", "string", "", func(e *EPrint) interface{} { return e.ISSN }},
+	{"isbn", "string", "", func(e *EPrint) interface{} { return e.ISBN }},
+	{"official_url", "string", "", func(e *EPrint) interface{} { return e.OfficialURL }},
+	{"collection", "string", "", func(e *EPrint) interface{} { return e.Collection }},
+	{"lastmod", "string", "last modified as YYYY-MM-DD hh:mm:ss", func(e *EPrint) interface{} { return e.LastModified }},
+	{"license", "string", "SPDX license identifier of the primary document or rights", licenseOf},
+	{"access_rights", "string", "openAccess, embargoedAccess, restrictedAccess or closedAccess as set by EPrint.SetAccessRights", func(e *EPrint) interface{} { return e.AccessRights }},
+}
+
+// csvValue renders a column value for CSV output, nil is empty
+func csvValue(val interface{}) string {
+	if val == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// CSVWriter writes EPrint records as CSV rows of CSVColumns
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter creates a CSVWriter writing to out
+func NewCSVWriter(out io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(out)}
+}
+
+// Write writes e as a CSV row, the header is written before the first row
+func (cw *CSVWriter) Write(e *EPrint) error {
+	if cw.header == false {
+		row := []string{}
+		for _, col := range CSVColumns {
+			row = append(row, col.Name)
+		}
+		if err := cw.w.Write(row); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	row := []string{}
+	for _, col := range CSVColumns {
+		row = append(row, csvValue(col.value(e)))
+	}
+	return cw.w.Write(row)
+}
+
+// Flush writes any buffered rows, returning any error from writing
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// WriteCSV writes eprints as CSV with a header row of CSVColumns
+func WriteCSV(out io.Writer, eprints []*EPrint) error {
+	cw := NewCSVWriter(out)
+	for _, e := range eprints {
+		if err := cw.Write(e); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+// WriteCSVSchema writes a Table Schema JSON document describing the
+// columns written by WriteCSV so the CSV can be loaded with types.
+func WriteCSVSchema(out io.Writer) error {
+	schema := map[string]interface{}{
+		"fields":     CSVColumns,
+		"primaryKey": "eprint_id",
+	}
+	src, err := json.MarshalIndent(schema, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", src)
+	return err
+}
+
+// column returns the column in CSVColumns named name
+func column(name string) (*Column, bool) {
+	for _, col := range CSVColumns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return nil, false
+}
+
+// Flatten returns the EPrint's CSVColumns as a map of column name to
+// value (e.g. "eprint_id", "title", "year", "doi").
+func (e *EPrint) Flatten() map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, col := range CSVColumns {
+		m[col.Name] = col.value(e)
+	}
+	return m
+}
+
+// Grid returns a row per EPrint holding the values of the named
+// CSVColumns in order (e.g. []string{"eprint_id", "title", "year",
+// "type", "doi"}) for use in reports.
+func Grid(eprints []*EPrint, names []string) ([][]interface{}, error) {
+	cols := []*Column{}
+	for _, name := range names {
+		col, ok := column(name)
+		if ok == false {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		cols = append(cols, col)
+	}
+	grid := [][]interface{}{}
+	for _, e := range eprints {
+		row := []interface{}{}
+		for _, col := range cols {
+			row = append(row, col.value(e))
+		}
+		grid = append(grid, row)
+	}
+	return grid, nil
+}
@@ -0,0 +1,121 @@
+package eprinttools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	e := new(EPrint)
+	e.EPrintID = 1234
+	e.Title = "A study, of things"
+	e.Date = "2019-03-02"
+	e.Creators = new(CreatorItemList)
+	e.Creators.AddItem(&Item{Name: &Name{Family: "Doe", Given: "Jane"}})
+	e.Creators.AddItem(&Item{Name: &Name{Family: "Smith", Given: "Robert"}})
+	e.RelatedURL = new(RelatedURLItemList)
+	e.RelatedURL.AddItem(&Item{URL: "10.1234/things.2019", Type: "doi"})
+	e.Rights = "http://creativecommons.org/licenses/by/4.0/"
+	e.SetAccessRights(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	undated := new(EPrint)
+	undated.EPrintID = 1235
+
+	buf := new(bytes.Buffer)
+	if err := WriteCSV(buf, []*EPrint{e, undated}); err != nil {
+		t.Errorf("WriteCSV() returned an error, %s", err)
+		t.FailNow()
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Errorf("can't read CSV, %s", err)
+		t.FailNow()
+	}
+	if len(rows) != 3 {
+		t.Errorf("expected header and two rows, got %d", len(rows))
+		t.FailNow()
+	}
+	record := map[string]string{}
+	for i, name := range rows[0] {
+		record[name] = rows[1][i]
+	}
+	expected := map[string]string{
+		"eprint_id":     "1234",
+		"title":         "A study, of things",
+		"creators":      "Doe, Jane; Smith, Robert",
+		"year":          "2019",
+		"doi":           "10.1234/things.2019",
+		"license":       "CC-BY-4.0",
+		"access_rights": "closedAccess",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("expected %s %q, got %q", k, v, record[k])
+		}
+	}
+	for i, name := range rows[0] {
+		if name == "year" && rows[2][i] != "" {
+			t.Errorf("expected empty year for undated record, got %q", rows[2][i])
+		}
+	}
+	//NOTE: columns added later go at the end so existing loaders keep working
+	if n := len(rows[0]); rows[0][n-2] != "license" || rows[0][n-1] != "access_rights" {
+		t.Errorf("expected license and access_rights to be the last columns, got %q", rows[0])
+	}
+
+	buf.Reset()
+	if err := WriteCSVSchema(buf); err != nil {
+		t.Errorf("WriteCSVSchema() returned an error, %s", err)
+		t.FailNow()
+	}
+	schema := struct {
+		Fields []map[string]string `json:"fields"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Errorf("can't read schema, %s", err)
+		t.FailNow()
+	}
+	if len(schema.Fields) != len(rows[0]) {
+		t.Errorf("expected %d schema fields, got %d", len(rows[0]), len(schema.Fields))
+	}
+	for i, field := range schema.Fields {
+		if field["name"] != rows[0][i] {
+			t.Errorf("expected schema field %q, got %q", rows[0][i], field["name"])
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	e := new(EPrint)
+	e.EPrintID = 1234
+	e.Title = "A study of things"
+	e.Type = "article"
+	e.Date = "2019-03-02"
+	e.DOI = "10.1234/things.2019"
+
+	grid, err := Grid([]*EPrint{e}, []string{"eprint_id", "title", "year", "type", "doi"})
+	if err != nil {
+		t.Errorf("Grid() returned an error, %s", err)
+		t.FailNow()
+	}
+	expected := []interface{}{1234, "A study of things", 2019, "article", "10.1234/things.2019"}
+	if len(grid) != 1 || len(grid[0]) != len(expected) {
+		t.Errorf("expected one row of %d columns, got %+v", len(expected), grid)
+		t.FailNow()
+	}
+	for i, val := range expected {
+		if grid[0][i] != val {
+			t.Errorf("expected column %d to be %v, got %v", i, val, grid[0][i])
+		}
+	}
+	if _, err := Grid([]*EPrint{e}, []string{"no_such_column"}); err == nil {
+		t.Errorf("expected an error for an unknown column")
+	}
+
+	m := e.Flatten()
+	if m["year"] != 2019 || m["title"] != "A study of things" {
+		t.Errorf("unexpected flattened record %+v", m)
+	}
+}
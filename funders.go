@@ -0,0 +1,89 @@
+package eprinttools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FunderRegistry maps funder agency names to Crossref Funder Registry
+// DOI (e.g. "National Science Foundation" to "10.13039/100000001").
+// Names not yet known are looked up with the CrossRef funders API and
+// remembered so the registry can be saved and reused as a cache.
+type FunderRegistry struct {
+	registry
+	// MailTo is passed to the CrossRef API to identify the client
+	MailTo string
+}
+
+// LoadFunderRegistry reads a registry saved as JSON by Save, if fName
+// doesn't exist an empty registry is returned.
+func LoadFunderRegistry(fName string) (*FunderRegistry, error) {
+	reg := new(FunderRegistry)
+	reg.APIURL = "https://api.crossref.org/funders"
+	if err := reg.load(fName); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// funderQuery searches the CrossRef funders API for agency returning
+// the funder DOI of an exact (case insensitive) name or alternate name
+// match, or an empty string if there isn't one.
+func (reg *FunderRegistry) funderQuery(agency string) (string, error) {
+	params := map[string]string{
+		"query": agency,
+		"rows":  "10",
+	}
+	if reg.MailTo != "" {
+		params["mailto"] = reg.MailTo
+	}
+	result := struct {
+		Message struct {
+			Items []struct {
+				ID       string   `json:"id"`
+				Name     string   `json:"name"`
+				AltNames []string `json:"alt-names"`
+			} `json:"items"`
+		} `json:"message"`
+	}{}
+	if err := reg.getJSON(params, &result); err != nil {
+		return "", err
+	}
+	for _, item := range result.Message.Items {
+		if matchName(agency, append([]string{item.Name}, item.AltNames...)...) {
+			return "10.13039/" + item.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// Lookup returns the funder DOI for agency, or an empty string if
+// the agency isn't in the Funder Registry. Failed queries aren't
+// remembered so the agency is looked up again next time.
+func (reg *FunderRegistry) Lookup(agency string) (string, error) {
+	return reg.lookup(agency, reg.funderQuery)
+}
+
+// Apply sets the FunderDOI of the EPrint's funders that don't have one.
+// Funders whose lookup fails are left without a DOI, the rest are still
+// looked up and the first error is returned.
+func (reg *FunderRegistry) Apply(e *EPrint) error {
+	var firstErr error
+	if e.Funders == nil {
+		return nil
+	}
+	for _, item := range e.Funders.Items {
+		if item.FunderDOI != "" || strings.TrimSpace(item.Agency) == "" {
+			continue
+		}
+		doi, err := reg.Lookup(item.Agency)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s, %s", item.Agency, err)
+			}
+			continue
+		}
+		item.FunderDOI = doi
+	}
+	return firstErr
+}
@@ -0,0 +1,99 @@
+package eprinttools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestFunderRegistry(t *testing.T) {
+	queries := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		switch r.URL.Query().Get("query") {
+		case "NSF":
+			fmt.Fprintf(w, `{"message": {"items": [
+  {"id": "100000001", "name": "National Science Foundation", "alt-names": ["NSF"]}
+]}}`)
+		default:
+			fmt.Fprintf(w, `{"message": {"items": [
+  {"id": "100000002", "name": "National Institutes of Health", "alt-names": ["NIH"]}
+]}}`)
+		}
+	}))
+	defer ts.Close()
+
+	fName := path.Join(t.TempDir(), "funders.json")
+	reg, err := LoadFunderRegistry(fName)
+	if err != nil {
+		t.Errorf("LoadFunderRegistry() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg.APIURL = ts.URL
+	e := new(EPrint)
+	e.Funders = new(FunderItemList)
+	e.Funders.AddItem(&Item{Agency: "NSF"})
+	e.Funders.AddItem(&Item{Agency: "nsf"})
+	e.Funders.AddItem(&Item{Agency: "Example Foundation"})
+	e.Funders.AddItem(&Item{Agency: "Keck Foundation", FunderDOI: "10.13039/100001201"})
+	if err := reg.Apply(e); err != nil {
+		t.Errorf("Apply() returned an error, %s", err)
+		t.FailNow()
+	}
+	expected := []string{"10.13039/100000001", "10.13039/100000001", "", "10.13039/100001201"}
+	for i, item := range e.Funders.Items {
+		if item.FunderDOI != expected[i] {
+			t.Errorf("expected funder DOI %q for %q, got %q", expected[i], item.Agency, item.FunderDOI)
+		}
+	}
+	if queries != 2 {
+		t.Errorf("expected 2 queries, got %d", queries)
+	}
+	if err := reg.Save(fName); err != nil {
+		t.Errorf("Save() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg, err = LoadFunderRegistry(fName)
+	if err != nil {
+		t.Errorf("LoadFunderRegistry() returned an error, %s", err)
+		t.FailNow()
+	}
+	if doi, ok := reg.IDs["nsf"]; ok == false || doi != "10.13039/100000001" {
+		t.Errorf("expected saved registry to have nsf, got %+v", reg.IDs)
+	}
+}
+
+func TestFunderRegistryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") == "Broken Foundation" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"message": {"items": [
+  {"id": "100000001", "name": "National Science Foundation", "alt-names": ["NSF"]}
+]}}`)
+	}))
+	defer ts.Close()
+
+	reg, err := LoadFunderRegistry(path.Join(t.TempDir(), "funders.json"))
+	if err != nil {
+		t.Errorf("LoadFunderRegistry() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg.APIURL = ts.URL
+	e := new(EPrint)
+	e.Funders = new(FunderItemList)
+	e.Funders.AddItem(&Item{Agency: "Broken Foundation"})
+	e.Funders.AddItem(&Item{Agency: "NSF"})
+	if err := reg.Apply(e); err == nil {
+		t.Errorf("expected Apply() to return an error")
+	}
+	if e.Funders.Items[0].FunderDOI != "" || e.Funders.Items[1].FunderDOI != "10.13039/100000001" {
+		t.Errorf("expected only NSF to have a funder DOI, got %q and %q", e.Funders.Items[0].FunderDOI, e.Funders.Items[1].FunderDOI)
+	}
+	if _, ok := reg.IDs["broken foundation"]; ok == true {
+		t.Errorf("expected the failed lookup not to be cached, got %+v", reg.IDs)
+	}
+}
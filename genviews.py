@@ -18,7 +18,7 @@ import progressbar
 
 from py_dataset import dataset
 
-from eprinttools import Configuration, Aggregator, Views, Subjects, Users, normalize_object, get_date_year, get_eprint_id, get_title, make_frame_date_title
+from eprinttools import Configuration, Aggregator, Views, Subjects, Users, Slugs, People, Groups, normalize_object, get_date_year, get_eprint_id, get_title, make_frame_date_title, is_tombstone
 
 #
 # CaltechES EPrint Site Layouts look like:
@@ -29,6 +29,7 @@ from eprinttools import Configuration, Aggregator, Views, Subjects, Users, norma
 #   Year -> year_list.json -> view/year/
 #   Item Category -> subjects_list.json > view/subject/
 #   Author -> people_list.json -> view/person-az/
+#   Title -> title-az_list.json -> view/title-az/
 #   Latest Additions -> latest_list.json -> cgi/latest/
 #
 # Unlinked types include view/ids/ and view/types/
@@ -50,10 +51,21 @@ def normalize_objects(objs, users, subjects):
         objs[i] = obj
     return objs
 
+#
+# remove_tombstones drops withdrawn records so they are not
+# listed in any view.
+#
+def remove_tombstones(objs):
+    l = []
+    for obj in objs:
+        if not is_tombstone(obj):
+            l.append(obj)
+    return l
+
 #
 # Build our this repository's aggregated views
 #
-def aggregate(cfg, views, users, subjects):
+def aggregate(cfg, views, users, subjects, slugs, people, groups):
     c_name = cfg.dataset
     err = make_frame_date_title(cfg)
     if err != '':
@@ -61,8 +73,9 @@ def aggregate(cfg, views, users, subjects):
     frame_name = 'date-title'
     aggregations = {}
     objs = dataset.frame_objects(c_name, frame_name)
+    objs = remove_tombstones(objs)
     objs = normalize_objects(objs, users, subjects)
-    aggregator = Aggregator(c_name, objs)
+    aggregator = Aggregator(c_name, objs, slugs, people, groups)
     view_keys = views.get_keys()
     for key in view_keys:
         aggregations[key] = aggregator.aggregate_by_view_name(key, subjects)
@@ -90,6 +103,18 @@ def landing_filter(obj, users, subjects):
     return normalize_object(obj, users, subjects)
 
 
+#
+# write_landing writes obj to the index.json of a landing page
+#
+def write_landing(cfg, key, obj):
+    src = json.dumps(obj)
+    p_name = os.path.join(cfg.htdocs, f'{key}')
+    os.makedirs(p_name, mode = 0o777, exist_ok = True)
+    f_name = os.path.join(p_name, 'index.json')
+    with open(f_name, 'w') as f:
+        f.write(src)
+
+
 #
 # generate_landings creates index.json to render index.md,
 # also deposits attachments in their relative paths.
@@ -121,6 +146,12 @@ def generate_landings(cfg, views, users, subjects):
             print(f'''
 WARNING: can't read {key} from {c_name}, {err}''')
             continue
+        # NOTE: tombstones only have enough metadata to render
+        # the withdrawn page, there are no attachments to copy.
+        if is_tombstone(obj):
+            write_landing(cfg, key, obj)
+            bar.update(i)
+            continue
         obj = landing_filter(obj, users, subjects)
                 # source location to S3 bucket!
         if cfg.include_documents:
@@ -156,23 +187,41 @@ cannot move to {f_name}, skipping''')
                 obj['primary_object']['url'] = os.path.join(".", rel_path[len(key)+1:], b_name)
         # NOTE: after handling the attachments we can write our updated
         # index.json file.
-        src = json.dumps(obj)
-        p_name = os.path.join(cfg.htdocs, f'{key}')
-        os.makedirs(p_name, mode = 0o777, exist_ok = True)
-        f_name = os.path.join(p_name, 'index.json')
-        with open(f_name, 'w') as f:
-            f.write(src)
+        write_landing(cfg, key, obj)
         bar.update(i)
     bar.finish()
     print(f'generated {tot} landing pages, {e_cnt} errors from {repo_name}')
 
 
+#
+# make_letters returns the letter navigation of an A-Z view (e.g.
+# person-az) and marks the first entry under each letter with the
+# anchor the navigation links to.
+#
+def make_letters(aggregation):
+    letters = []
+    for obj in aggregation:
+        if not 'letter' in obj:
+            return []
+        letter = obj['letter']
+        if (len(letters) == 0) or (letters[-1]['label'] != letter):
+            anchor = f'letter-{letter.lower()}'
+            obj['anchor'] = anchor
+            letters.append({ 'key': anchor, 'label': letter })
+    return letters
+
 def make_view(view, p_name, aggregation):
     if not os.path.exists(p_name):
         os.makedirs(p_name, mode = 0o777, exist_ok = True)
     if not os.path.exists(p_name):
         print(f'WARNING {p_name} does not exist, skipping {view}')
         return ''
+    letters = make_letters(aggregation)
+    if len(letters) > 0:
+        f_name = os.path.join(p_name, f'{view}_letters.json')
+        with open(f_name, 'w') as f:
+            src = json.dumps(letters)
+            f.write(src)
     f_name = os.path.join(p_name, f'{view}_list.json')
     print(f'writing "{view}" -> {f_name}')
     with open(f_name, 'w') as f:
@@ -211,8 +260,27 @@ def generate_metadata_structure(cfg):
     users.load_users(cfg.users)
     subjects = Subjects()
     subjects.load_subjects(cfg.subjects)
+    # NOTE: the slug map keeps the view paths assigned by earlier
+    # builds, it is optional.
+    slugs = Slugs()
+    if cfg.slugs != '':
+        slugs.load_slugs(cfg.slugs)
+    # NOTE: the people authority merges name variants on the
+    # person pages, it is optional.
+    people = None
+    if cfg.people != '':
+        people = People()
+        people.load_people(cfg.people)
+    # NOTE: the group alias map consolidates local group names
+    # on the group pages, it is optional.
+    groups = None
+    if cfg.groups != '':
+        groups = Groups()
+        groups.load_groups(cfg.groups)
     generate_directories(cfg, views.get_keys())
-    aggregations = aggregate(cfg, views, users, subjects)
+    aggregations = aggregate(cfg, views, users, subjects, slugs, people, groups)
+    if cfg.slugs != '':
+        slugs.save_slugs(cfg.slugs)
     print(f'Found {len(aggregations)} aggregations: ', end = '\n\t')
     for i, key in enumerate(aggregations):
         if i > 0:
@@ -222,6 +290,8 @@ def generate_metadata_structure(cfg):
         else:
             print(f'Nothing to aggregate for {key}')
     print('')
+    if (groups != None) and (len(groups.unmatched) > 0):
+        print(f'{len(groups.unmatched)} local group values not in {cfg.groups}, see group_report.py')
     generate_views(cfg, views, aggregations)
     generate_landings(cfg, views, users, subjects)
 
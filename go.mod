@@ -7,4 +7,5 @@ require (
 	github.com/caltechlibrary/crossrefapi v0.0.5
 	github.com/caltechlibrary/dataciteapi v0.0.5
 	golang.org/x/crypto v0.0.0-20210218145215-b8e89b74b9df
+	golang.org/x/text v0.3.6
 )
@@ -0,0 +1,56 @@
+#!/usr/bin/env python3
+
+#
+# group_report.py reports the local group values in a dataset
+# collection which the group alias map doesn't cover.
+#
+
+import os
+import sys
+import json
+
+from py_dataset import dataset
+
+from eprinttools import Configuration, Groups, get_groups
+
+def usage():
+    app = os.path.basename(sys.argv[0])
+    print(f'''
+USAGE: {app} CONFIG_JSON
+
+{app} reads the local group values of each record
+in the dataset collection and lists those which are not
+a group name or alias in the group alias file named by
+"groups" in the configuration. Values are listed with
+the number of records using them, most frequent first.
+
+  {app} config.json
+
+''')
+
+if __name__ == "__main__":
+    if len(sys.argv) != 2:
+        usage()
+        sys.exit(1)
+    f_name = sys.argv[1]
+    if not os.path.exists(f_name):
+        print(f'ERROR: Missing {f_name} file.')
+        sys.exit(1)
+    cfg = Configuration()
+    if cfg.load_config(f_name) and cfg.required(['dataset', 'groups']):
+        c_name = cfg.dataset
+        groups = Groups()
+        groups.load_groups(cfg.groups)
+        for key in dataset.keys(c_name):
+            obj, err = dataset.read(c_name, key)
+            if err != '':
+                print(f'WARNING: skipping {key} in {c_name}, {err}')
+                continue
+            for value in get_groups(obj):
+                groups.get_group(value)
+        unmatched = groups.unmatched_list()
+        for item in unmatched:
+            print(f"{item['count']}\t{item['value']}")
+        print(f'{len(unmatched)} local group values not in {cfg.groups}')
+    else:
+        sys.exit(1)
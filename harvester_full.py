@@ -9,7 +9,7 @@ import os
 import sys
 import json
 
-from eprinttools import harvest_init, harvest, harvest_keys
+from eprinttools import harvest_init, harvest, harvest_keys, harvest_tombstones
 from eprinttools import Configuration
 
 def usage():
@@ -21,6 +21,8 @@ USAGE: {app} CONGIF_JSON [KEY [KEY] ...]
 documents. It converts the EPrintXML into JSON which is
 stored in a dataset collection and stores the related
 records and EPrintXML as attachments to the JSON reccord.
+When no keys are given records which are no longer in the
+repository are replaced by a tombstone.
 
   {app} config.json \\
      123
@@ -54,7 +56,8 @@ if __name__ == "__main__":
         if err != '':
             print(err)
             sys.exit(1)
-        if len(keys) == 0:
+        all_keys = (len(keys) == 0)
+        if all_keys:
             keys = harvest_keys()
             if len(keys) == 0:
                 print("No keys found")
@@ -63,6 +66,14 @@ if __name__ == "__main__":
         if err != '':
             print(err)
             sys.exit(1)
+        # NOTE: when we have the repository's complete key list records
+        # EPrints no longer lists are replaced with tombstones.
+        if all_keys:
+            cnt, err = harvest_tombstones(keys)
+            if err != '':
+                print(err)
+                sys.exit(1)
+            print(f'{cnt} records no longer in the repository replaced with tombstones')
         print('OK')
     else:
         sys.exit(1)
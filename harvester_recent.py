@@ -9,7 +9,7 @@ import os
 import sys
 import json
 
-from eprinttools import harvest_init, harvest, harvest_keys
+from eprinttools import harvest_init, harvest, harvest_keys, harvest_tombstones
 from eprinttools import Configuration
 
 def usage():
@@ -28,7 +28,8 @@ in the last N days are harvested.
 stored in a dataset collection and stores the related
 records and EPrintXML as attachments to the JSON reccord.
 If the configuration has 'include_documents' set to true
-then the documents are harvested too.
+then the documents are harvested too. Records which are no
+longer in the repository are replaced by a tombstone.
 
   {app} config.json
 
@@ -63,7 +64,8 @@ if __name__ == "__main__":
         if err != '':
             print(err)
             sys.exit(1)
-        if len(keys) == 0:
+        all_keys = (len(keys) == 0)
+        if all_keys:
             keys = harvest_keys()
             if len(keys) == 0:
                 print("No keys found")
@@ -76,6 +78,14 @@ if __name__ == "__main__":
         if err != '':
             print(err)
             sys.exit(1)
+        # NOTE: when we have the repository's complete key list records
+        # EPrints no longer lists are replaced with tombstones.
+        if all_keys:
+            cnt, err = harvest_tombstones(keys)
+            if err != '':
+                print(err)
+                sys.exit(1)
+            print(f'{cnt} records no longer in the repository replaced with tombstones')
         print('OK')
     else:
         sys.exit(1)
@@ -10,7 +10,7 @@ from lunr import lunr
 
 from py_dataset import dataset
 
-from eprinttools import Configuration, Subjects
+from eprinttools import Configuration, Subjects, is_tombstone
 
 #
 # Apply scheme setups the data for search results and indexing.
@@ -123,6 +123,10 @@ def build_index(cfg):
             print(f'WARNING: skipping {key} in {c_name}, {err}')
             e_cnt += 1
             continue
+        # NOTE: withdrawn records are not searchable
+        if is_tombstone(obj):
+            bar.update(i)
+            continue
         obj, err = apply_scheme(obj, subjects, htdocs)
         if err != '':
             print(f'WARNING: skipping {kay} in {c_name}, apply scheme: {err}')
@@ -0,0 +1,126 @@
+package eprinttools
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// jatsTag matches any markup, used when the JATS isn't well formed
+	jatsTag = regexp.MustCompile(`<[^>]*>`)
+
+	// jatsHTML maps JATS inline elements to HTML elements
+	jatsHTML = map[string]string{
+		"p":         "p",
+		"italic":    "i",
+		"bold":      "b",
+		"sup":       "sup",
+		"sub":       "sub",
+		"underline": "u",
+	}
+)
+
+// jatsParagraphs decodes JATS markup (e.g. a CrossRef abstract like
+// `<jats:p>Some <jats:italic>text</jats:italic></jats:p>`) calling fn
+// for each start element, end element and text. Titles (e.g.
+// "Abstract") are skipped.
+func jatsParagraphs(src string, fn func(tok xml.Token)) error {
+	dec := xml.NewDecoder(strings.NewReader("<jats>" + src + "</jats>"))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	inTitle := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "title" {
+				inTitle++
+			} else if inTitle == 0 {
+				fn(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "title" {
+				inTitle--
+			} else if inTitle == 0 {
+				fn(t)
+			}
+		case xml.CharData:
+			if inTitle == 0 {
+				fn(t)
+			}
+		}
+	}
+}
+
+// collapseSpace replaces runs of white space with a single space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// JATSToText converts JATS markup (e.g. an abstract from CrossRef) to
+// plain text, paragraphs are separated by a blank line.
+func JATSToText(src string) string {
+	paragraphs := []string{}
+	var sb strings.Builder
+	flush := func() {
+		if s := collapseSpace(sb.String()); s != "" {
+			paragraphs = append(paragraphs, s)
+		}
+		sb.Reset()
+	}
+	err := jatsParagraphs(src, func(tok xml.Token) {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "p" || t.Name.Local == "sec" {
+				flush()
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" || t.Name.Local == "sec" {
+				flush()
+			}
+		case xml.CharData:
+			sb.Write(t)
+		}
+	})
+	if err != nil {
+		//NOTE: not well formed, fallback to removing the tags
+		return collapseSpace(html.UnescapeString(jatsTag.ReplaceAllString(src, " ")))
+	}
+	flush()
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// JATSToHTML converts JATS markup (e.g. an abstract from CrossRef) to
+// HTML, paragraphs and inline formatting (italic, bold, sup, sub) are
+// kept and other elements are removed.
+func JATSToHTML(src string) string {
+	var sb strings.Builder
+	err := jatsParagraphs(src, func(tok xml.Token) {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if elem, ok := jatsHTML[t.Name.Local]; ok == true {
+				sb.WriteString("<" + elem + ">")
+			}
+		case xml.EndElement:
+			if elem, ok := jatsHTML[t.Name.Local]; ok == true {
+				sb.WriteString("</" + elem + ">")
+			}
+		case xml.CharData:
+			sb.WriteString(html.EscapeString(string(t)))
+		}
+	})
+	if err != nil {
+		//NOTE: not well formed, fallback to plain text
+		return html.EscapeString(JATSToText(src))
+	}
+	return strings.TrimSpace(sb.String())
+}
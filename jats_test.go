@@ -0,0 +1,25 @@
+package eprinttools
+
+import (
+	"testing"
+)
+
+func TestJATS(t *testing.T) {
+	src := `<jats:title>Abstract</jats:title>
+<jats:p>The   <jats:italic>in vivo</jats:italic> response of H<jats:sub>2</jats:sub>O &amp; ice.</jats:p>
+<jats:p>A second paragraph.</jats:p>`
+	expected := "The in vivo response of H2O & ice.\n\nA second paragraph."
+	if s := JATSToText(src); s != expected {
+		t.Errorf("JATSToText() expected %q, got %q", expected, s)
+	}
+	expected = "<p>The   <i>in vivo</i> response of H<sub>2</sub>O &amp; ice.</p>\n<p>A second paragraph.</p>"
+	if s := JATSToHTML(src); s != expected {
+		t.Errorf("JATSToHTML() expected %q, got %q", expected, s)
+	}
+	// Not well formed JATS falls back to removing tags
+	src = `<jats:p>Broken <jats:italic>markup</jats:p>`
+	expected = "Broken markup"
+	if s := JATSToText(src); s != expected {
+		t.Errorf("JATSToText() expected %q, got %q", expected, s)
+	}
+}
@@ -13,7 +13,7 @@ from subprocess import run, Popen, PIPE
 
 import progressbar
 
-from eprinttools import Configuration, Views
+from eprinttools import Configuration, Views, is_tombstone
 
 #
 # mkpage wraps the mkpage command from mkpage using the
@@ -212,14 +212,19 @@ def make_view(cfg, view, label, site_title, organization):
     tot = len(objs)
     if tot == 0:
         content = 'Nothing available.'
-    assemble(cfg, html_filename, template_name, [
+    data = [
         kv('organization', 'text', organization),
         kv('site_title', 'text', site_title),
         kv('page_title', 'text', page_title),
         kv('title', 'text', title),
         kv('content', 'text', content),
         kv('listing', '', os.path.join(cfg.htdocs, list_data))
-    ])
+    ]
+    # NOTE: A-Z views (e.g. person-az) include letter navigation
+    letters_data = os.path.join(cfg.htdocs, 'view', view, f'{view}_letters.json')
+    if os.path.exists(letters_data):
+        data.append(kv('letters', '', letters_data))
+    assemble(cfg, html_filename, template_name, data)
     bar = progressbar.ProgressBar(
           max_value = tot,
             widgets = [
@@ -288,11 +293,16 @@ def build_landing_pages(cfg):
             ], redirect_stdout=False)
     for i, key in enumerate(keys):
         obj = load_objects(cfg, f'{key}/index.json')
-        title =  obj['title']
+        title = f'Item {key}'
+        if 'title' in obj:
+            title = obj['title']
         page_title = f'{title} - {site_title}'
         object_path = os.path.join(cfg.htdocs, f'{key}', f'index.json')
         html_filename = f'{key}/index.html'
         template_name = 'landing-page-html.tmpl'
+        if is_tombstone(obj):
+            page_title = f'Withdrawn: {title} - {site_title}'
+            template_name = 'tombstone-page-html.tmpl'
         assemble(cfg, html_filename, template_name, [
             kv('organization', 'text', organization),
             kv('site_title', 'text', site_title),
@@ -0,0 +1,78 @@
+package eprinttools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mod11_2 computes the ISO 7064 MOD 11-2 check character for the
+// digits of an ORCID or ISNI (the first 15 characters).
+func mod11_2(digits string) string {
+	total := 0
+	for _, r := range digits {
+		total = (total + int(r-'0')) * 2
+	}
+	check := (12 - (total % 11)) % 11
+	if check == 10 {
+		return "X"
+	}
+	return fmt.Sprintf("%d", check)
+}
+
+// normalizeIdentifier16 strips the given prefixes, spaces and hyphens
+// from s and validates the result as 15 digits plus an ISO 7064 MOD 11-2
+// check character. Returns the 16 character identifier.
+func normalizeIdentifier16(label string, s string, prefixes []string) (string, error) {
+	id := strings.TrimSpace(s)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(strings.ToLower(id), prefix) {
+			id = strings.TrimSpace(id[len(prefix):])
+			break
+		}
+	}
+	id = strings.ToUpper(strings.NewReplacer("-", "", " ", "", "/", "").Replace(id))
+	if len(id) != 16 {
+		return "", fmt.Errorf("%q is not a valid %s, expected 16 characters", s, label)
+	}
+	for _, r := range id[0:15] {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%q is not a valid %s, expected digits", s, label)
+		}
+	}
+	if check := mod11_2(id[0:15]); id[15:] != check {
+		return "", fmt.Errorf("%q is not a valid %s, bad checksum", s, label)
+	}
+	return id, nil
+}
+
+// NormalizeORCID takes an ORCID in any of the forms we see in
+// metadata (e.g. "https://orcid.org/0000-0002-1825-0097",
+// "0000000218250097") and returns the canonical form
+// "0000-0002-1825-0097". An error is returned if the ORCID's checksum
+// is not valid.
+func NormalizeORCID(s string) (string, error) {
+	id, err := normalizeIdentifier16("ORCID", s, []string{
+		"https://orcid.org/",
+		"http://orcid.org/",
+		"orcid.org/",
+		"orcid:",
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%s-%s", id[0:4], id[4:8], id[8:12], id[12:16]), nil
+}
+
+// NormalizeISNI takes an ISNI (e.g. "0000 0001 2103 2683",
+// "https://isni.org/isni/0000000121032683") and returns the canonical
+// 16 character form without spaces. An error is returned if the ISNI's
+// checksum is not valid.
+func NormalizeISNI(s string) (string, error) {
+	return normalizeIdentifier16("ISNI", s, []string{
+		"https://isni.org/isni/",
+		"http://isni.org/isni/",
+		"isni.org/isni/",
+		"isni:",
+		"isni",
+	})
+}
@@ -0,0 +1,52 @@
+package eprinttools
+
+import (
+	"testing"
+)
+
+func TestNormalizeORCID(t *testing.T) {
+	expected := "0000-0002-1694-233X"
+	for _, s := range []string{
+		"0000-0002-1694-233X",
+		"0000-0002-1694-233x",
+		"https://orcid.org/0000-0002-1694-233X",
+		"http://orcid.org/0000-0002-1694-233X",
+		"orcid.org/0000-0002-1694-233X",
+		"0000000216942 33X",
+	} {
+		orcid, err := NormalizeORCID(s)
+		if err != nil {
+			t.Errorf("NormalizeORCID(%q) returned an error, %s", s, err)
+		} else if orcid != expected {
+			t.Errorf("NormalizeORCID(%q) expected %q, got %q", s, expected, orcid)
+		}
+	}
+	if orcid, err := NormalizeORCID("0000-0002-1825-0097"); err != nil || orcid != "0000-0002-1825-0097" {
+		t.Errorf("expected 0000-0002-1825-0097 to be valid, got %q, %s", orcid, err)
+	}
+	for _, s := range []string{"", "0000-0002-1825-0098", "0000-0002-1825", "000A-0002-1825-0097"} {
+		if _, err := NormalizeORCID(s); err == nil {
+			t.Errorf("NormalizeORCID(%q) expected an error", s)
+		}
+	}
+}
+
+func TestNormalizeISNI(t *testing.T) {
+	expected := "0000000121032683"
+	for _, s := range []string{
+		"0000 0001 2103 2683",
+		"0000000121032683",
+		"https://isni.org/isni/0000000121032683",
+		"ISNI 0000 0001 2103 2683",
+	} {
+		isni, err := NormalizeISNI(s)
+		if err != nil {
+			t.Errorf("NormalizeISNI(%q) returned an error, %s", s, err)
+		} else if isni != expected {
+			t.Errorf("NormalizeISNI(%q) expected %q, got %q", s, expected, isni)
+		}
+	}
+	if _, err := NormalizeISNI("0000 0001 2103 2684"); err == nil {
+		t.Errorf("expected a checksum error")
+	}
+}
@@ -0,0 +1,165 @@
+#!/usr/bin/env python3
+
+#
+# people_authority.py reviews and edits the people authority file
+# genviews.py uses to merge the name variants and creator ids of a
+# person into one person page.
+#
+
+import os
+import sys
+import json
+
+from py_dataset import dataset
+
+from eprinttools import Configuration, People, slugify
+
+def usage():
+    app = os.path.basename(sys.argv[0])
+    print(f'''
+USAGE: {app} CONFIG_JSON COMMAND [PARAMETERS]
+
+{app} reviews and edits the people authority file
+named by "people" in the configuration. Each person
+has a key (used in their page's path), a label and the
+creator ids and name variants merged into them.
+
+COMMANDS
+
+  list                    list the people in the authority file
+  suggest                 list creator ids and names from the dataset
+                          which look like variants of one person
+  add KEY LABEL           add a person
+  label KEY LABEL         change a person's label
+  add-id KEY ID           map a creator id to a person
+  add-name KEY NAME       map a name variant to a person
+  remove-id ID            remove a creator id mapping
+  remove-name NAME        remove a name variant mapping
+  merge KEY OTHER_KEY     merge OTHER_KEY's ids and names into KEY
+
+  {app} config.json add-name Doiel-R-S "Doiel, Robert"
+
+This maps the name "Doiel, Robert" to the person with key
+Doiel-R-S.
+
+''')
+
+def creator_name(creator):
+    family, given = '', ''
+    if 'name' in creator:
+        if 'family' in creator['name']:
+            family = creator['name']['family'].strip()
+        if 'given' in creator['name']:
+            given = creator['name']['given'].strip()
+    if (family != '') and (given != ''):
+        return f'{family}, {given}'
+    return family
+
+#
+# suggest groups the creators found in the dataset collection by
+# creator id and by family name and first initial, reporting the
+# groups with more than one variant not already in the authority.
+#
+def suggest(c_name, people):
+    by_id = {}
+    by_initial = {}
+    for key in dataset.keys(c_name):
+        obj, err = dataset.read(c_name, key)
+        if err != '':
+            print(f'WARNING: skipping {key} in {c_name}, {err}')
+            continue
+        if (not 'creators' in obj) or (not 'items' in obj['creators']):
+            continue
+        for creator in obj['creators']['items']:
+            name = creator_name(creator)
+            if name == '':
+                continue
+            creator_id = ''
+            if 'id' in creator:
+                creator_id = creator['id']
+            if people.get_person(creator_id, name) != None:
+                continue
+            if creator_id != '':
+                if not creator_id in by_id:
+                    by_id[creator_id] = []
+                if not name in by_id[creator_id]:
+                    by_id[creator_id].append(name)
+            family, _, given = name.partition(',')
+            initial = slugify(f'{family} {given.strip()[0:1]}')
+            if not initial in by_initial:
+                by_initial[initial] = []
+            if not name in by_initial[initial]:
+                by_initial[initial].append(name)
+    for creator_id in sorted(by_id):
+        if len(by_id[creator_id]) > 1:
+            print(f'id {creator_id}: ' + '; '.join(by_id[creator_id]))
+    for initial in sorted(by_initial):
+        if len(by_initial[initial]) > 1:
+            print(f'names {initial}: ' + '; '.join(by_initial[initial]))
+
+def require_person(people, key):
+    if not people.has_person(key):
+        print(f'ERROR: {key} is not in the people authority')
+        sys.exit(1)
+
+if __name__ == "__main__":
+    if len(sys.argv) < 3:
+        usage()
+        sys.exit(1)
+    f_name, cmd, params = sys.argv[1], sys.argv[2], sys.argv[3:]
+    if not os.path.exists(f_name):
+        print(f'ERROR: Missing {f_name} file.')
+        sys.exit(1)
+    cfg = Configuration()
+    if not (cfg.load_config(f_name) and cfg.required(['people'])):
+        sys.exit(1)
+    people = People()
+    people.load_people(cfg.people)
+    expected = {
+        'list': 0, 'suggest': 0, 'add': 2, 'label': 2,
+        'add-id': 2, 'add-name': 2, 'remove-id': 1,
+        'remove-name': 1, 'merge': 2
+    }
+    if not cmd in expected:
+        print(f'ERROR: unknown command {cmd}')
+        sys.exit(1)
+    if len(params) != expected[cmd]:
+        print(f'ERROR: {cmd} expects {expected[cmd]} parameter(s)')
+        sys.exit(1)
+    if cmd == 'list':
+        for person in people.person_list():
+            print(f"{person['key']}\t{person['label']}\t" + '; '.join(person['ids'] + person['names']))
+        sys.exit(0)
+    if cmd == 'suggest':
+        if not cfg.required(['dataset']):
+            sys.exit(1)
+        suggest(cfg.dataset, people)
+        sys.exit(0)
+    if cmd == 'add':
+        people.add_person(params[0], params[1])
+    elif cmd == 'label':
+        require_person(people, params[0])
+        people.add_person(params[0], params[1])
+    elif cmd == 'add-id':
+        require_person(people, params[0])
+        people.add_id(params[0], params[1])
+    elif cmd == 'add-name':
+        require_person(people, params[0])
+        people.add_name(params[0], params[1])
+    elif cmd == 'remove-id':
+        if people.remove_id(params[0]) == '':
+            print(f'ERROR: {params[0]} is not mapped')
+            sys.exit(1)
+    elif cmd == 'remove-name':
+        if people.remove_name(params[0]) == '':
+            print(f'ERROR: {params[0]} is not mapped')
+            sys.exit(1)
+    elif cmd == 'merge':
+        require_person(people, params[0])
+        require_person(people, params[1])
+        if params[0] == params[1]:
+            print(f'ERROR: can not merge {params[0]} into itself')
+            sys.exit(1)
+        people.merge(params[0], params[1])
+    people.save_people(cfg.people)
+    print('OK')
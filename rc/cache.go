@@ -0,0 +1,166 @@
+package rc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tmpPrefix starts the names of responses being written to the cache
+const tmpPrefix = ".tmp-"
+
+// Cache is an optional on-disk cache of GET responses keyed by URL.
+// It is useful during development and repeated partial harvests.
+type Cache struct {
+	// Dir holds the cached responses
+	Dir string
+	// TTL is how long a cached response is used, zero means forever
+	TTL time.Duration
+	// MaxSize is the total size in bytes of the responses kept, when
+	// exceeded the oldest responses are removed until the cache is
+	// back under nine tenths of MaxSize. Zero means no limit.
+	MaxSize int64
+
+	mu sync.Mutex
+	// size estimates the bytes in Dir once counted is set
+	size    int64
+	counted bool
+}
+
+// fileName returns the cache filename for a URL
+func (cache *Cache) fileName(u string) string {
+	return filepath.Join(cache.Dir, fmt.Sprintf("%x", sha1.Sum([]byte(u))))
+}
+
+// Get returns the cached response body for URL u if present and not
+// expired.
+func (cache *Cache) Get(u string) ([]byte, bool) {
+	fName := cache.fileName(u)
+	info, err := os.Stat(fName)
+	if err != nil {
+		return nil, false
+	}
+	if cache.TTL > 0 && time.Since(info.ModTime()) > cache.TTL {
+		return nil, false
+	}
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return nil, false
+	}
+	return src, true
+}
+
+// Put saves the response body for URL u. The response is written to
+// a temporary file and renamed so a concurrent Get never reads part of
+// it. The cache is pruned once it grows past MaxSize.
+func (cache *Cache) Put(u string, src []byte) error {
+	if err := os.MkdirAll(cache.Dir, 0775); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(cache.Dir, tmpPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0664); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), cache.fileName(u)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if cache.MaxSize <= 0 {
+		return nil
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	//NOTE: the size is only an estimate (a replaced response is
+	// counted twice), prune() reads Dir for the actual size.
+	if cache.counted == false {
+		cache.counted = true
+		return cache.prune()
+	}
+	cache.size += int64(len(src))
+	if cache.size > cache.MaxSize {
+		return cache.prune()
+	}
+	return nil
+}
+
+// remove drops the cached response for u, e.g. after it is updated
+func (cache *Cache) remove(u string) error {
+	err := os.Remove(cache.fileName(u))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// prune removes the oldest responses if the cache is larger than
+// MaxSize until it is no larger than nine tenths of MaxSize, so it
+// isn't pruned again on the next Put. The caller holds cache.mu.
+func (cache *Cache) prune() error {
+	infos, err := ioutil.ReadDir(cache.Dir)
+	if err != nil {
+		return err
+	}
+	files := []os.FileInfo{}
+	total := int64(0)
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), tmpPrefix) == false {
+			files = append(files, info)
+			total += info.Size()
+		}
+	}
+	cache.size = total
+	if total <= cache.MaxSize {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	limit := cache.MaxSize - cache.MaxSize/10
+	for _, info := range files {
+		if total <= limit {
+			break
+		}
+		// NOTE: another request may have already pruned the file
+		if err := os.Remove(filepath.Join(cache.Dir, info.Name())); err != nil && os.IsNotExist(err) == false {
+			return err
+		}
+		total -= info.Size()
+	}
+	cache.size = total
+	return nil
+}
+
+// cacheBody reads body saving it in cache for URL u and returns
+// a reader for the content
+func (cache *Cache) cacheBody(u string, body io.ReadCloser) (io.ReadCloser, error) {
+	defer body.Close()
+	src, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(u, src); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(src)), nil
+}
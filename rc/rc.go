@@ -1,13 +1,20 @@
 package rc
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	//"log"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -40,6 +47,21 @@ const (
 	Shibboleth
 )
 
+// ErrNotModified is returned by Request and Stream when a conditional
+// request is answered with 304 Not Modified.
+var ErrNotModified = errors.New("not modified")
+
+// validatorsMu guards Validators maps shared between RestAPI
+// instances used concurrently
+var validatorsMu sync.Mutex
+
+// Validator holds the ETag and Last-Modified values of a response so a
+// later request for the same resource can be made conditional.
+type Validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
 type RestAPI struct {
 	u        *url.URL
 	id       string
@@ -50,6 +72,19 @@ type RestAPI struct {
 
 	// Timeout is the client time out period, default is 10 seconds
 	Timeout time.Duration
+
+	// Validators if not nil holds the ETag and Last-Modified values
+	// by request URI. GET requests for a URI found in Validators are
+	// sent as conditional requests (If-None-Match, If-Modified-Since)
+	// and successful responses update it.
+	Validators map[string]Validator
+
+	// Cache if not nil is used to store and reuse GET responses
+	Cache *Cache
+
+	// Client if not nil is used for requests instead of a new
+	// client per request, e.g. to share a tuned transport.
+	Client *http.Client
 }
 
 // New creates a new Rest Client RestAPI instance
@@ -75,6 +110,43 @@ func New(apiURL string, authType int, clientID, clientSecret string) (*RestAPI,
 	}, nil
 }
 
+// ConfigureTLS returns an HTTP client that trusts the certificates in
+// caFile in addition to the system pool and, if certFile and keyFile
+// are set, presents them as a client certificate. Empty values are
+// ignored. Proxies set via HTTP_PROXY, HTTPS_PROXY and NO_PROXY are
+// honored. Use it as the Client of a RestAPI or EPrintsAPI.
+func ConfigureTLS(caFile, certFile, keyFile string) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		src, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if ok := pool.AppendCertsFromPEM(src); ok == false {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate %s, %s", certFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := &http.Transport{}
+	if t, ok := http.DefaultTransport.(*http.Transport); ok == true {
+		transport = t.Clone()
+	}
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
 // AddHeader sets the header strings to send with the request
 func (api *RestAPI) AddHeader(ky, value string) {
 	if api.headers == nil {
@@ -175,18 +247,14 @@ func (api *RestAPI) shibbolethLogin() error {
 	return fmt.Errorf("shibbolethLogin() not implemented")
 }
 
-// Request contacts the Rest API and returns the full read response body, and error
-// payload is the used to build the URL Query object (e.g. ?key=value&key1=value...)
-func (api *RestAPI) Request(method, docPath string, payload map[string]string) ([]byte, error) {
+// newRequest builds an *http.Request for method and docPath setting up
+// authentication and headers. payload is used to build the URL Query
+// object (e.g. ?key=value&key1=value...)
+func (api *RestAPI) newRequest(method, docPath string, payload map[string]string) (*http.Request, error) {
 	var (
 		req *http.Request
 		err error
 	)
-	// Create a http client
-	client := &http.Client{
-		Timeout: api.Timeout,
-	}
-
 	// NOT: if api.token not set we should just go ahead and oAuthLogin.
 	if api.token == "" {
 		if err := api.Login(); err != nil {
@@ -195,8 +263,16 @@ func (api *RestAPI) Request(method, docPath string, payload map[string]string) (
 	}
 
 	// NOTE: we want a copy the URL in Rest API object and update copy with the docPath
-	u := api.u
-	u.Path = docPath
+	// docPath may also be a request URI (escaped path and query string)
+	u := *api.u
+	p, err := url.Parse(docPath)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = p.Path, p.RawPath
+	if p.RawQuery != "" {
+		u.RawQuery = p.RawQuery
+	}
 
 	// NOTE: Based the HTTP method we want, we build our request appropriately
 	switch strings.ToUpper(method) {
@@ -205,37 +281,215 @@ func (api *RestAPI) Request(method, docPath string, payload map[string]string) (
 		if err != nil {
 			return nil, err
 		}
-		// NOTE: If we're using Basic Auth setup the request with it
-		if api.authType == BasicAuth {
-			req.SetBasicAuth(api.id, api.secret)
-		}
-		// NOTE: If we've authenticated we need to path the auth token
-		if len(api.token) > 0 {
-			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", api.token))
-		}
+		api.authorize(req)
 		// NOTE: We need to indicate the format we want
 		req.Header.Add("Accept", "application/json")
 
-		// NOTE: Build our payload to pass in the URL since this is a GET
-		qry := req.URL.Query()
-		for key, value := range payload {
-			qry.Add(key, value)
+		// NOTE: Build our payload to pass in the URL since this is a GET,
+		// without one the query is sent as given (e.g. a signed URL)
+		if len(payload) > 0 {
+			qry := req.URL.Query()
+			for key, value := range payload {
+				qry.Add(key, value)
+			}
+			req.URL.RawQuery = qry.Encode()
+		}
+		// NOTE: If we've seen this resource before make it conditional
+		validatorsMu.Lock()
+		v, ok := api.Validators[req.URL.RequestURI()]
+		validatorsMu.Unlock()
+		if ok == true {
+			if v.ETag != "" {
+				req.Header.Add("If-None-Match", v.ETag)
+			}
+			if v.LastModified != "" {
+				req.Header.Add("If-Modified-Since", v.LastModified)
+			}
 		}
-		req.URL.RawQuery = qry.Encode()
 	default:
 		return nil, fmt.Errorf("Do not know how to make a %s request", method)
 	}
+	return req, nil
+}
+
+// authorize adds the credentials for api to req
+func (api *RestAPI) authorize(req *http.Request) {
+	// NOTE: If we're using Basic Auth setup the request with it
+	if api.authType == BasicAuth {
+		req.SetBasicAuth(api.id, api.secret)
+	}
+	// NOTE: If we've authenticated we need to path the auth token
+	if len(api.token) > 0 {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", api.token))
+	}
+}
+
+// relatedPaths returns docPath and, for a path below a record such as
+// /rest/eprint/123/title.txt, the record's own path /rest/eprint/123.xml
+func relatedPaths(docPath string) []string {
+	paths := []string{docPath}
+	parts := strings.Split(docPath, "/")
+	for i, part := range parts {
+		if part == "rest" && len(parts) > i+3 {
+			paths = append(paths, strings.Join(parts[:i+3], "/")+".xml")
+			break
+		}
+	}
+	return paths
+}
+
+// Send makes a PUT, POST or DELETE request to docPath with body (which
+// may be nil) of contentType, returning the response body. Any cached
+// response or validator for docPath and its parent record is removed.
+func (api *RestAPI) Send(method, docPath, contentType string, body io.Reader) ([]byte, error) {
+	method = strings.ToUpper(method)
+	switch method {
+	case "PUT", "POST", "DELETE":
+	default:
+		return nil, fmt.Errorf("Do not know how to send a %s request", method)
+	}
+	if api.token == "" {
+		if err := api.Login(); err != nil {
+			return nil, err
+		}
+	}
+	u := api.u
+	u.Path = docPath
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	api.authorize(req)
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+	for k, v := range api.headers {
+		req.Header.Add(k, v)
+	}
+	client := api.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: api.Timeout,
+		}
+	}
+	// NOTE: a change to a field also changes its parent record so both
+	// are dropped from the cache and validators
+	for _, p := range relatedPaths(docPath) {
+		pu := api.u
+		pu.Path = p
+		pu.RawQuery = ""
+		if api.Cache != nil {
+			api.Cache.remove(pu.Redacted())
+		}
+		if api.Validators != nil {
+			validatorsMu.Lock()
+			delete(api.Validators, pu.RequestURI())
+			validatorsMu.Unlock()
+		}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	src, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// NOTE: Redacted() keeps any password in the URL out of logs
+		return src, fmt.Errorf("%s for %s", resp.Status, req.URL.Redacted())
+	}
+	return src, nil
+}
+
+// Request contacts the Rest API and returns the full read response body, and error
+// payload is the used to build the URL Query object (e.g. ?key=value&key1=value...)
+func (api *RestAPI) Request(method, docPath string, payload map[string]string) ([]byte, error) {
+	body, err := api.Stream(method, docPath, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// Stream contacts the Rest API and returns the response body unread
+// so large responses can be processed incrementally. The caller is
+// responsible for closing the returned body.
+func (api *RestAPI) Stream(method, docPath string, payload map[string]string) (io.ReadCloser, error) {
+	// Create a http client
+	client := api.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: api.Timeout,
+		}
+	}
+	req, err := api.newRequest(method, docPath, payload)
+	if err != nil {
+		return nil, err
+	}
+	// NOTE: Redacted() keeps credentials out of the cache key
+	cacheKey := req.URL.Redacted()
+	if api.Cache != nil && req.Method == "GET" {
+		if src, ok := api.Cache.Get(cacheKey); ok == true {
+			return ioutil.NopCloser(bytes.NewReader(src)), nil
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	if resp.StatusCode == 200 {
-		src, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+		if api.Validators != nil {
+			v := Validator{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			}
+			if v.ETag != "" || v.LastModified != "" {
+				validatorsMu.Lock()
+				api.Validators[req.URL.RequestURI()] = v
+				validatorsMu.Unlock()
+			}
 		}
-		return src, nil
+		if api.Cache != nil && req.Method == "GET" {
+			return api.Cache.cacheBody(cacheKey, resp.Body)
+		}
+		return resp.Body, nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	// NOTE: Redacted() keeps any password in the URL out of logs
+	return nil, fmt.Errorf("%s for %s", resp.Status, req.URL.Redacted())
+}
+
+// LoadValidators reads a JSON file of Validators saved by
+// SaveValidators. A missing file returns an empty map.
+func LoadValidators(fName string) (map[string]Validator, error) {
+	validators := map[string]Validator{}
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return validators, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(src, &validators); err != nil {
+		return nil, fmt.Errorf("can't read validators from %s, %s", fName, err)
+	}
+	return validators, nil
+}
+
+// SaveValidators writes validators to a JSON file so conditional
+// requests can be made across harvest runs.
+func SaveValidators(fName string, validators map[string]Validator) error {
+	validatorsMu.Lock()
+	src, err := json.MarshalIndent(validators, "", "  ")
+	validatorsMu.Unlock()
+	if err != nil {
+		return err
 	}
-	return nil, fmt.Errorf("%s for %s", resp.Status, u.String())
+	return ioutil.WriteFile(fName, src, 0664)
 }
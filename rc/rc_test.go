@@ -1,9 +1,23 @@
 package rc
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Testing Rest Client access against ORCID REST API
@@ -95,3 +109,211 @@ func TestURLStringAuth(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestConfigureTLS(t *testing.T) {
+	dName := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Errorf("can't generate key, %s", err)
+		t.FailNow()
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test CA"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Errorf("can't create certificate, %s", err)
+		t.FailNow()
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Errorf("can't marshal key, %s", err)
+		t.FailNow()
+	}
+	certFile, keyFile := path.Join(dName, "cert.pem"), path.Join(dName, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	defaultTransport := http.DefaultTransport
+	client, err := ConfigureTLS(certFile, certFile, keyFile)
+	if err != nil {
+		t.Errorf("ConfigureTLS() returned an error, %s", err)
+		t.FailNow()
+	}
+	if http.DefaultTransport != defaultTransport {
+		t.Errorf("expected the default transport to be left alone")
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if ok == false || transport.TLSClientConfig == nil {
+		t.Errorf("expected client transport to have a TLS config")
+		t.FailNow()
+	}
+	if transport.TLSClientConfig.RootCAs == nil || len(transport.TLSClientConfig.Certificates) != 1 {
+		t.Errorf("expected CA bundle and client certificate, got %+v", transport.TLSClientConfig)
+	}
+	if _, err := ConfigureTLS(keyFile, "", ""); err == nil {
+		t.Errorf("expected an error for a CA bundle without certificates")
+	}
+}
+
+func TestConditionalRequest(t *testing.T) {
+	etag := `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		fmt.Fprintf(w, "<eprints></eprints>")
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, AuthNone, "", "")
+	if err != nil {
+		t.Errorf("Can't create API, %s", err)
+		t.FailNow()
+	}
+	api.Validators = map[string]Validator{}
+	src, err := api.Request("GET", "/rest/eprint/1.xml", map[string]string{})
+	if err != nil {
+		t.Errorf("first request failed, %s", err)
+		t.FailNow()
+	}
+	if string(src) != "<eprints></eprints>" {
+		t.Errorf("unexpected response %q", src)
+	}
+	if v, ok := api.Validators["/rest/eprint/1.xml"]; ok == false || v.ETag != etag {
+		t.Errorf("expected validator for /rest/eprint/1.xml, got %+v", api.Validators)
+	}
+	if _, err := api.Request("GET", "/rest/eprint/1.xml", map[string]string{}); err != ErrNotModified {
+		t.Errorf("expected ErrNotModified, got %v", err)
+	}
+
+	fName := path.Join(t.TempDir(), "validators.json")
+	if err := SaveValidators(fName, api.Validators); err != nil {
+		t.Errorf("SaveValidators() returned an error, %s", err)
+		t.FailNow()
+	}
+	validators, err := LoadValidators(fName)
+	if err != nil {
+		t.Errorf("LoadValidators() returned an error, %s", err)
+		t.FailNow()
+	}
+	if validators["/rest/eprint/1.xml"] != api.Validators["/rest/eprint/1.xml"] {
+		t.Errorf("expected %+v, got %+v", api.Validators, validators)
+	}
+}
+
+func TestCache(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, "response for %s", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, AuthNone, "", "")
+	if err != nil {
+		t.Errorf("Can't create API, %s", err)
+		t.FailNow()
+	}
+	api.Cache = &Cache{Dir: path.Join(t.TempDir(), "cache"), TTL: time.Hour}
+	for i := 0; i < 2; i++ {
+		src, err := api.Request("GET", "/rest/eprint/1.xml", map[string]string{})
+		if err != nil {
+			t.Errorf("request %d failed, %s", i, err)
+			t.FailNow()
+		}
+		if string(src) != "response for /rest/eprint/1.xml" {
+			t.Errorf("unexpected response %q", src)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected one request to the server, got %d", requests)
+	}
+
+	// NOTE: each response is 31 bytes so only one fits
+	api.Cache.MaxSize = 40
+	if _, err := api.Request("GET", "/rest/eprint/2.xml", map[string]string{}); err != nil {
+		t.Errorf("request failed, %s", err)
+		t.FailNow()
+	}
+	files, _ := ioutil.ReadDir(api.Cache.Dir)
+	if len(files) != 1 {
+		t.Errorf("expected one cached response, got %d", len(files))
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := &Cache{Dir: path.Join(t.TempDir(), "cache"), MaxSize: 100}
+	src := []byte(strings.Repeat("x", 30))
+	then := time.Now().Add(-time.Hour)
+	for i, u := range []string{"a", "b", "c", "d"} {
+		if err := cache.Put(u, src); err != nil {
+			t.Errorf("Put(%q) failed, %s", u, err)
+			t.FailNow()
+		}
+		// NOTE: give each response its own age so the oldest are pruned
+		mtime := then.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(cache.fileName(u), mtime, mtime)
+		files, _ := ioutil.ReadDir(cache.Dir)
+		if i < 3 && len(files) != i+1 {
+			t.Errorf("expected no pruning under MaxSize, got %d files after %d puts", len(files), i+1)
+		}
+	}
+	files, _ := ioutil.ReadDir(cache.Dir)
+	if len(files) != 3 {
+		t.Errorf("expected the cache pruned to three responses, got %d", len(files))
+	}
+	if _, ok := cache.Get("a"); ok == true {
+		t.Errorf("expected the oldest response to be pruned")
+	}
+	for _, u := range []string{"b", "c", "d"} {
+		if _, ok := cache.Get(u); ok == false {
+			t.Errorf("expected %q to be kept", u)
+		}
+	}
+}
+
+func TestSendEvictsParent(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			requests++
+			w.Header().Set("ETag", `"v1"`)
+		}
+		fmt.Fprintf(w, "response for %s", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, AuthNone, "", "")
+	if err != nil {
+		t.Errorf("Can't create API, %s", err)
+		t.FailNow()
+	}
+	api.Cache = &Cache{Dir: path.Join(t.TempDir(), "cache"), TTL: time.Hour}
+	api.Validators = map[string]Validator{}
+	if _, err := api.Request("GET", "/rest/eprint/1.xml", map[string]string{}); err != nil {
+		t.Errorf("request failed, %s", err)
+		t.FailNow()
+	}
+	if _, err := api.Send("PUT", "/rest/eprint/1/title.txt", "text/plain", nil); err != nil {
+		t.Errorf("send failed, %s", err)
+		t.FailNow()
+	}
+	if _, ok := api.Validators["/rest/eprint/1.xml"]; ok == true {
+		t.Errorf("expected validator for /rest/eprint/1.xml to be removed")
+	}
+	if _, err := api.Request("GET", "/rest/eprint/1.xml", map[string]string{}); err != nil {
+		t.Errorf("request failed, %s", err)
+		t.FailNow()
+	}
+	if requests != 2 {
+		t.Errorf("expected the parent record to be fetched again, got %d requests", requests)
+	}
+}
@@ -0,0 +1,118 @@
+package eprinttools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registry maps names (e.g. funder agencies or organizations) to the
+// identifiers a web API returns for them. Names looked up are
+// remembered so the registry can be saved and reused as a cache.
+// FunderRegistry and RORRegistry embed it.
+type registry struct {
+	// APIURL is the web API names are looked up with
+	APIURL string
+	// Client is used for API requests, if nil a client with a 30
+	// second timeout is used.
+	Client *http.Client
+	// IDs maps lower case names to identifiers, an empty identifier
+	// records a name that wasn't found.
+	IDs map[string]string
+
+	mu sync.Mutex
+}
+
+// load reads the names and identifiers saved as JSON by Save, if
+// fName doesn't exist the registry is left empty.
+func (reg *registry) load(fName string) error {
+	reg.IDs = map[string]string{}
+	src, err := ioutil.ReadFile(fName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(src, &reg.IDs); err != nil {
+		return fmt.Errorf("%s, %s", fName, err)
+	}
+	return nil
+}
+
+// Save writes the registry's names and identifiers to fName as JSON
+func (reg *registry) Save(fName string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	src, err := json.MarshalIndent(reg.IDs, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fName, src, 0664)
+}
+
+// getJSON sends a GET request to APIURL with params decoding the JSON
+// response into v.
+func (reg *registry) getJSON(params map[string]string, v interface{}) error {
+	u, err := url.Parse(reg.APIURL)
+	if err != nil {
+		return err
+	}
+	qry := u.Query()
+	for key, val := range params {
+		qry.Set(key, val)
+	}
+	u.RawQuery = qry.Encode()
+	client := reg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s for %s", resp.Status, u.String())
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// lookup returns the identifier for name calling query if the name
+// hasn't been looked up before. Failed queries aren't remembered so
+// the name is looked up again next time.
+func (reg *registry) lookup(name string, query func(string) (string, error)) (string, error) {
+	name = strings.TrimSpace(name)
+	key := strings.ToLower(name)
+	reg.mu.Lock()
+	id, ok := reg.IDs[key]
+	reg.mu.Unlock()
+	if ok == true {
+		return id, nil
+	}
+	id, err := query(name)
+	if err != nil {
+		return "", err
+	}
+	reg.mu.Lock()
+	reg.IDs[key] = id
+	reg.mu.Unlock()
+	return id, nil
+}
+
+// matchName returns true if one of names is name ignoring case and
+// surrounding spaces.
+func matchName(name string, names ...string) bool {
+	for _, s := range names {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package eprinttools
+
+import (
+	"strings"
+	"time"
+)
+
+// LicenseInfo identifies a license by its SPDX identifier and
+// canonical URL (e.g. "CC-BY-4.0",
+// "https://creativecommons.org/licenses/by/4.0/").
+type LicenseInfo struct {
+	SPDX string `json:"spdx"`
+	URL  string `json:"url"`
+}
+
+// licenses lists the licenses NormalizeLicense knows along with the
+// EPrints license codes (the document license field) for them.
+var licenses = []struct {
+	LicenseInfo
+	codes []string
+}{
+	{LicenseInfo{"CC-BY-3.0", "https://creativecommons.org/licenses/by/3.0/"}, []string{"cc_by"}},
+	{LicenseInfo{"CC-BY-SA-3.0", "https://creativecommons.org/licenses/by-sa/3.0/"}, []string{"cc_by_sa"}},
+	{LicenseInfo{"CC-BY-ND-3.0", "https://creativecommons.org/licenses/by-nd/3.0/"}, []string{"cc_by_nd"}},
+	{LicenseInfo{"CC-BY-NC-3.0", "https://creativecommons.org/licenses/by-nc/3.0/"}, []string{"cc_by_nc"}},
+	{LicenseInfo{"CC-BY-NC-SA-3.0", "https://creativecommons.org/licenses/by-nc-sa/3.0/"}, []string{"cc_by_nc_sa"}},
+	{LicenseInfo{"CC-BY-NC-ND-3.0", "https://creativecommons.org/licenses/by-nc-nd/3.0/"}, []string{"cc_by_nc_nd"}},
+	{LicenseInfo{"CC-BY-4.0", "https://creativecommons.org/licenses/by/4.0/"}, []string{"cc_by_4"}},
+	{LicenseInfo{"CC-BY-SA-4.0", "https://creativecommons.org/licenses/by-sa/4.0/"}, []string{"cc_by_sa_4"}},
+	{LicenseInfo{"CC-BY-ND-4.0", "https://creativecommons.org/licenses/by-nd/4.0/"}, []string{"cc_by_nd_4"}},
+	{LicenseInfo{"CC-BY-NC-4.0", "https://creativecommons.org/licenses/by-nc/4.0/"}, []string{"cc_by_nc_4"}},
+	{LicenseInfo{"CC-BY-NC-SA-4.0", "https://creativecommons.org/licenses/by-nc-sa/4.0/"}, []string{"cc_by_nc_sa_4"}},
+	{LicenseInfo{"CC-BY-NC-ND-4.0", "https://creativecommons.org/licenses/by-nc-nd/4.0/"}, []string{"cc_by_nc_nd_4"}},
+	{LicenseInfo{"CC0-1.0", "https://creativecommons.org/publicdomain/zero/1.0/"}, []string{"cc0", "cc_zero"}},
+	{LicenseInfo{"CC-PDDC", "https://creativecommons.org/licenses/publicdomain/"}, []string{"cc_public_domain"}},
+	{LicenseInfo{"GPL-2.0-only", "https://www.gnu.org/licenses/old-licenses/gpl-2.0.html"}, []string{"cc_gnu_gpl", "gnu_gpl"}},
+	{LicenseInfo{"LGPL-2.1-only", "https://www.gnu.org/licenses/old-licenses/lgpl-2.1.html"}, []string{"cc_gnu_lgpl", "gnu_lgpl"}},
+}
+
+// licenseKey normalizes a license code, SPDX identifier or URL for
+// lookup, e.g. "http://creativecommons.org/licenses/by/4.0/legalcode"
+// becomes "creativecommons.org/licenses/by/4.0".
+func licenseKey(s string) string {
+	key := strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range []string{"https://", "http://", "www."} {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	if strings.HasPrefix(key, "creativecommons.org/") {
+		if i := strings.Index(key, "/legalcode"); i > 0 {
+			key = key[:i]
+		}
+		if i := strings.Index(key, "/deed"); i > 0 {
+			key = key[:i]
+		}
+	}
+	return strings.TrimSuffix(key, "/")
+}
+
+// licenseIndex maps licenseKey values to the licenses
+var licenseIndex = map[string]*LicenseInfo{}
+
+func init() {
+	for i := range licenses {
+		info := &licenses[i].LicenseInfo
+		licenseIndex[licenseKey(info.SPDX)] = info
+		licenseIndex[licenseKey(info.URL)] = info
+		for _, code := range licenses[i].codes {
+			licenseIndex[licenseKey(code)] = info
+		}
+	}
+}
+
+// NormalizeLicense returns the SPDX identifier and canonical URL for
+// an EPrints license code (e.g. "cc_by_4"), SPDX identifier (e.g.
+// "cc-by-4.0") or license URL (e.g.
+// "http://creativecommons.org/licenses/by/4.0/legalcode"). If the
+// license isn't known it returns nil and false.
+func NormalizeLicense(s string) (*LicenseInfo, bool) {
+	info, ok := licenseIndex[licenseKey(s)]
+	if ok == false {
+		return nil, false
+	}
+	return &LicenseInfo{SPDX: info.SPDX, URL: info.URL}, true
+}
+
+// License returns the normalized license of the EPrint's primary
+// document, or if that isn't known of the rights statement.
+func (e *EPrint) License() (*LicenseInfo, bool) {
+	if e.Documents != nil {
+		for _, doc := range *e.Documents {
+			if (doc.Placement == 1 || doc.Pos == 1) && doc.Content != "supplemental" {
+				if info, ok := NormalizeLicense(doc.License); ok == true {
+					return info, true
+				}
+			}
+		}
+	}
+	return NormalizeLicense(e.Rights)
+}
+
+// Access rights values, these are the info:eu-repo/semantics terms
+// used by OpenAIRE.
+const (
+	OpenAccess       = "openAccess"
+	EmbargoedAccess  = "embargoedAccess"
+	RestrictedAccess = "restrictedAccess"
+	ClosedAccess     = "closedAccess"
+)
+
+// coarAccessRights maps access rights values to the COAR access
+// rights vocabulary (http://purl.org/coar/access_right)
+var coarAccessRights = map[string]string{
+	OpenAccess:       "http://purl.org/coar/access_right/c_abf2",
+	EmbargoedAccess:  "http://purl.org/coar/access_right/c_f1cf",
+	RestrictedAccess: "http://purl.org/coar/access_right/c_16ec",
+	ClosedAccess:     "http://purl.org/coar/access_right/c_14cb",
+}
+
+// AccessRightsURI returns the info:eu-repo and COAR URI for an access
+// rights value (e.g. "openAccess"), or empty strings if not known.
+func AccessRightsURI(accessRights string) (string, string) {
+	coar, ok := coarAccessRights[accessRights]
+	if ok == false {
+		return "", ""
+	}
+	return "info:eu-repo/semantics/" + accessRights, coar
+}
+
+// embargoed returns true if doc has an embargo date (YYYY, YYYY-MM or
+// YYYY-MM-DD) after now
+func (doc *Document) embargoed(now time.Time) bool {
+	embargo := strings.TrimSpace(doc.DateEmbargo)
+	if embargo == "" {
+		return false
+	}
+	today := now.Format("2006-01-02")
+	if len(embargo) < len(today) {
+		today = today[:len(embargo)]
+	}
+	return embargo > today
+}
+
+// SetAccessRights sets the EPrint's AccessRights field as of now, the
+// caller picks now so every record of an export or harvest agrees.
+func (e *EPrint) SetAccessRights(now time.Time) {
+	e.AccessRights = e.GetAccessRights(now)
+}
+
+// GetAccessRights derives the access rights of the EPrint as of now
+// from its documents' security and embargo dates. It is openAccess if
+// a (non supplemental) document is public, embargoedAccess if one is
+// under embargo, restrictedAccess if the documents are limited to
+// users or staff and closedAccess (metadata only) if there are none.
+func (e *EPrint) GetAccessRights(now time.Time) string {
+	if e.Documents == nil {
+		return ClosedAccess
+	}
+	accessRights := ClosedAccess
+	for _, doc := range *e.Documents {
+		if doc.Content == "supplemental" || doc.Main == "indexcodes.txt" {
+			continue
+		}
+		switch {
+		case doc.embargoed(now):
+			accessRights = EmbargoedAccess
+		case doc.Security == "public":
+			return OpenAccess
+		case accessRights == ClosedAccess:
+			accessRights = RestrictedAccess
+		}
+	}
+	return accessRights
+}
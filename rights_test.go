@@ -0,0 +1,76 @@
+package eprinttools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	for s, expected := range map[string]string{
+		"cc_by_4":   "CC-BY-4.0",
+		"CC_BY":     "CC-BY-3.0",
+		"cc-by-4.0": "CC-BY-4.0",
+		"http://creativecommons.org/licenses/by-nc/4.0/legalcode": "CC-BY-NC-4.0",
+		"https://creativecommons.org/licenses/by-sa/4.0/deed.en":  "CC-BY-SA-4.0",
+		"https://creativecommons.org/publicdomain/zero/1.0":       "CC0-1.0",
+		"cc_public_domain": "CC-PDDC",
+	} {
+		info, ok := NormalizeLicense(s)
+		if ok == false {
+			t.Errorf("expected %q to be normalized", s)
+			continue
+		}
+		if info.SPDX != expected {
+			t.Errorf("NormalizeLicense(%q) expected %q, got %q", s, expected, info.SPDX)
+		}
+	}
+	if info, ok := NormalizeLicense("cc_by_nc_nd_4"); ok == false || info.URL != "https://creativecommons.org/licenses/by-nc-nd/4.0/" {
+		t.Errorf("unexpected license %+v", info)
+	}
+	for _, s := range []string{"", "other", "All rights reserved"} {
+		if _, ok := NormalizeLicense(s); ok == true {
+			t.Errorf("expected %q not to be normalized", s)
+		}
+	}
+
+	e := new(EPrint)
+	e.Rights = "https://creativecommons.org/licenses/by/4.0/"
+	e.Documents = &DocumentList{
+		&Document{Pos: 1, License: "cc_by_nc_4"},
+	}
+	if info, ok := e.License(); ok == false || info.SPDX != "CC-BY-NC-4.0" {
+		t.Errorf("expected the primary document's license, got %+v", info)
+	}
+	(*e.Documents)[0].License = "other"
+	if info, ok := e.License(); ok == false || info.SPDX != "CC-BY-4.0" {
+		t.Errorf("expected the rights license, got %+v", info)
+	}
+}
+
+func TestGetAccessRights(t *testing.T) {
+	now := time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)
+	e := new(EPrint)
+	if s := e.GetAccessRights(now); s != ClosedAccess {
+		t.Errorf("expected %q without documents, got %q", ClosedAccess, s)
+	}
+	e.Documents = &DocumentList{
+		&Document{Pos: 1, Security: "validuser"},
+		&Document{Pos: 2, Security: "public", Content: "supplemental"},
+	}
+	if s := e.GetAccessRights(now); s != RestrictedAccess {
+		t.Errorf("expected %q, got %q", RestrictedAccess, s)
+	}
+	(*e.Documents)[0].DateEmbargo = "2021-07"
+	if s := e.GetAccessRights(now); s != EmbargoedAccess {
+		t.Errorf("expected %q, got %q", EmbargoedAccess, s)
+	}
+	(*e.Documents)[0].DateEmbargo = "2021-06-15"
+	(*e.Documents)[0].Security = "public"
+	if s := e.GetAccessRights(now); s != OpenAccess {
+		t.Errorf("expected %q once the embargo ends, got %q", OpenAccess, s)
+	}
+	euRepo, coar := AccessRightsURI(OpenAccess)
+	if euRepo != "info:eu-repo/semantics/openAccess" || coar != "http://purl.org/coar/access_right/c_abf2" {
+		t.Errorf("unexpected access rights URI %q, %q", euRepo, coar)
+	}
+}
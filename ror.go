@@ -0,0 +1,94 @@
+package eprinttools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RORRegistry maps organization names (e.g. funder agencies and local
+// groups) to Research Organization Registry (ROR) identifiers (e.g.
+// "California Institute of Technology" to "https://ror.org/05dxps055").
+// Names not yet known are looked up with the ROR API and remembered so
+// the registry can be saved and reused as a cache.
+type RORRegistry struct {
+	registry
+}
+
+// LoadRORRegistry reads a registry saved as JSON by Save, if fName
+// doesn't exist an empty registry is returned.
+func LoadRORRegistry(fName string) (*RORRegistry, error) {
+	reg := new(RORRegistry)
+	reg.APIURL = "https://api.ror.org/organizations"
+	if err := reg.load(fName); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// rorQuery searches the ROR API for name returning the ROR identifier
+// of an exact (case insensitive) name, alias or label match, or an
+// empty string if there isn't one.
+func (reg *RORRegistry) rorQuery(name string) (string, error) {
+	result := struct {
+		Items []struct {
+			ID      string   `json:"id"`
+			Name    string   `json:"name"`
+			Aliases []string `json:"aliases"`
+			Labels  []struct {
+				Label string `json:"label"`
+			} `json:"labels"`
+		} `json:"items"`
+	}{}
+	if err := reg.getJSON(map[string]string{"query": name}, &result); err != nil {
+		return "", err
+	}
+	for _, item := range result.Items {
+		names := append([]string{item.Name}, item.Aliases...)
+		for _, label := range item.Labels {
+			names = append(names, label.Label)
+		}
+		if matchName(name, names...) {
+			return item.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// Lookup returns the ROR identifier for name, or an empty string if
+// the organization isn't in ROR.
+func (reg *RORRegistry) Lookup(name string) (string, error) {
+	return reg.lookup(name, reg.rorQuery)
+}
+
+// Apply sets the ROR of the EPrint's funder agencies and local groups
+// that don't have one. Items whose lookup fails are left without a
+// ROR, the rest are still looked up and the first error is returned.
+func (reg *RORRegistry) Apply(e *EPrint) error {
+	var firstErr error
+	items := []*Item{}
+	if e.Funders != nil {
+		items = append(items, e.Funders.Items...)
+	}
+	if e.LocalGroup != nil {
+		items = append(items, e.LocalGroup.Items...)
+	}
+	for _, item := range items {
+		//NOTE: funders are named by agency, local groups by value
+		name := strings.TrimSpace(item.Agency)
+		if name == "" {
+			name = strings.TrimSpace(item.Value)
+		}
+		if item.ROR != "" || name == "" {
+			continue
+		}
+		ror, err := reg.Lookup(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s, %s", name, err)
+			}
+			continue
+		}
+		item.ROR = ror
+	}
+	return firstErr
+}
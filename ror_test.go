@@ -0,0 +1,82 @@
+package eprinttools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRORRegistry(t *testing.T) {
+	queries := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		switch r.URL.Query().Get("query") {
+		case "Broken Foundation":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case "National Science Foundation":
+			fmt.Fprintf(w, `{"items": [
+  {"id": "https://ror.org/021nxhr62", "name": "National Science Foundation", "aliases": [], "labels": []}
+]}`)
+		default:
+			fmt.Fprintf(w, `{"items": [
+  {"id": "https://ror.org/05dxps055", "name": "California Institute of Technology", "aliases": ["Caltech"], "labels": []}
+]}`)
+		}
+	}))
+	defer ts.Close()
+
+	fName := path.Join(t.TempDir(), "ror.json")
+	reg, err := LoadRORRegistry(fName)
+	if err != nil {
+		t.Errorf("LoadRORRegistry() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg.APIURL = ts.URL
+	e := new(EPrint)
+	e.Funders = new(FunderItemList)
+	e.Funders.AddItem(&Item{Agency: "National Science Foundation"})
+	e.Funders.AddItem(&Item{Agency: "Broken Foundation"})
+	e.LocalGroup = new(LocalGroupItemList)
+	e.LocalGroup.AddItem(&Item{Value: "Caltech"})
+	e.LocalGroup.AddItem(&Item{Value: "Seismological Laboratory"})
+	if err := reg.Apply(e); err == nil {
+		t.Errorf("expected Apply() to return an error for Broken Foundation")
+	}
+	if e.Funders.Items[0].ROR != "https://ror.org/021nxhr62" || e.Funders.Items[1].ROR != "" {
+		t.Errorf("unexpected funder ROR %q and %q", e.Funders.Items[0].ROR, e.Funders.Items[1].ROR)
+	}
+	if e.LocalGroup.Items[0].ROR != "https://ror.org/05dxps055" || e.LocalGroup.Items[1].ROR != "" {
+		t.Errorf("unexpected local group ROR %q and %q", e.LocalGroup.Items[0].ROR, e.LocalGroup.Items[1].ROR)
+	}
+	if _, ok := reg.IDs["broken foundation"]; ok == true {
+		t.Errorf("expected the failed lookup not to be cached, got %+v", reg.IDs)
+	}
+	src, err := json.Marshal(e.LocalGroup.Items[0])
+	if err != nil {
+		t.Errorf("can't marshal item, %s", err)
+	} else if strings.Contains(string(src), `"ror":"https://ror.org/05dxps055"`) == false {
+		t.Errorf("expected ror in JSON, got %s", src)
+	}
+
+	if err := reg.Save(fName); err != nil {
+		t.Errorf("Save() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg, err = LoadRORRegistry(fName)
+	if err != nil {
+		t.Errorf("LoadRORRegistry() returned an error, %s", err)
+		t.FailNow()
+	}
+	reg.APIURL = ts.URL
+	queries = 0
+	if ror, err := reg.Lookup("caltech"); err != nil || ror != "https://ror.org/05dxps055" {
+		t.Errorf("expected saved registry to have caltech, got %q, %v", ror, err)
+	}
+	if queries != 0 {
+		t.Errorf("expected cached lookups, got %d queries", queries)
+	}
+}
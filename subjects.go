@@ -0,0 +1,171 @@
+package eprinttools
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SubjectName is a label for a subject in a language
+type SubjectName struct {
+	Name string `xml:"name" json:"name"`
+	Lang string `xml:"lang,omitempty" json:"lang,omitempty"`
+}
+
+// Subject is an entry in the EPrints subject tree
+// (e.g. /rest/subject/ChemEng.xml)
+type Subject struct {
+	XMLName     xml.Name       `xml:"subject" json:"-"`
+	SubjectID   string         `xml:"subjectid" json:"subject_id"`
+	Names       []*SubjectName `xml:"name>item" json:"name,omitempty"`
+	Parents     []string       `xml:"parents>item" json:"parents,omitempty"`
+	Ancestors   []string       `xml:"ancestors>item" json:"ancestors,omitempty"`
+	Depositable string         `xml:"depositable,omitempty" json:"depositable,omitempty"`
+}
+
+// Label returns the subject's name in lang, falling back to the first
+// name or the subject id.
+func (subject *Subject) Label(lang string) string {
+	for _, name := range subject.Names {
+		if name.Lang == lang {
+			return name.Name
+		}
+	}
+	if len(subject.Names) > 0 {
+		return subject.Names[0].Name
+	}
+	return subject.SubjectID
+}
+
+// User is an EPrints user account (e.g. /rest/user/1.xml)
+type User struct {
+	XMLName  xml.Name `xml:"user" json:"-"`
+	UserID   int      `xml:"userid" json:"user_id"`
+	Username string   `xml:"username" json:"username"`
+	UserType string   `xml:"usertype,omitempty" json:"usertype,omitempty"`
+	Name     *Name    `xml:"name,omitempty" json:"name,omitempty"`
+	EMail    string   `xml:"email,omitempty" json:"email,omitempty"`
+	Dept     string   `xml:"dept,omitempty" json:"dept,omitempty"`
+	Org      string   `xml:"org,omitempty" json:"org,omitempty"`
+	Joined   string   `xml:"joined,omitempty" json:"joined,omitempty"`
+}
+
+// listIDs returns the ids listed by the REST API for a dataset
+// (e.g. /rest/subject/ lists ChemEng.xml)
+func (api *EPrintsAPI) listIDs(dataset string) ([]string, error) {
+	rest, err := api.restClient()
+	if err != nil {
+		return nil, err
+	}
+	rest.Cache = api.Cache
+	p := path.Join(api.URL.Path, "rest", dataset) + "/"
+	body, err := rest.Stream("GET", p, map[string]string{})
+	if err != nil {
+		return nil, fmt.Errorf("requested %s, %s", p, err)
+	}
+	defer body.Close()
+	ids := []string{}
+	m := make(map[string]bool)
+	err = readEPrintIDs(body, func(val string) error {
+		if strings.HasSuffix(val, ".xml") == true {
+			id := strings.TrimSuffix(val, ".xml")
+			if _, seen := m[id]; seen == false {
+				m[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// getDatasetXML retrieves /rest/<dataset>/<id>.xml decoding it into v
+func (api *EPrintsAPI) getDatasetXML(dataset, id string, v interface{}) error {
+	rest, err := api.restClient()
+	if err != nil {
+		return err
+	}
+	rest.Cache = api.Cache
+	p := path.Join(api.URL.Path, "rest", dataset, id+".xml")
+	src, err := rest.Request("GET", p, map[string]string{})
+	if err != nil {
+		return fmt.Errorf("requested %s, %s", p, err)
+	}
+	return DecodeXML(src, v)
+}
+
+// ListSubjectIDs returns the subject ids in the repository's subject
+// tree (/rest/subject/)
+func (api *EPrintsAPI) ListSubjectIDs() ([]string, error) {
+	return api.listIDs("subject")
+}
+
+// GetSubject returns the subject with id (e.g. "ChemEng")
+func (api *EPrintsAPI) GetSubject(id string) (*Subject, error) {
+	data := struct {
+		Subjects []*Subject `xml:"subject"`
+	}{}
+	if err := api.getDatasetXML("subject", id, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Subjects) != 1 {
+		return nil, fmt.Errorf("expected one subject for %s, got %d", id, len(data.Subjects))
+	}
+	return data.Subjects[0], nil
+}
+
+// GetSubjects returns the repository's subject tree as a map of
+// subject id to subject, the parents of each subject link the tree.
+func (api *EPrintsAPI) GetSubjects() (map[string]*Subject, error) {
+	ids, err := api.ListSubjectIDs()
+	if err != nil {
+		return nil, err
+	}
+	subjects := map[string]*Subject{}
+	for _, id := range ids {
+		subject, err := api.GetSubject(id)
+		if err != nil {
+			return nil, err
+		}
+		subjects[subject.SubjectID] = subject
+	}
+	return subjects, nil
+}
+
+// ListUserIDs returns the user ids of the repository's accounts
+// (/rest/user/), this requires an administrator's credentials.
+func (api *EPrintsAPI) ListUserIDs() ([]string, error) {
+	return api.listIDs("user")
+}
+
+// GetUser returns the user account with id
+func (api *EPrintsAPI) GetUser(id string) (*User, error) {
+	data := struct {
+		Users []*User `xml:"user"`
+	}{}
+	if err := api.getDatasetXML("user", id, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Users) != 1 {
+		return nil, fmt.Errorf("expected one user for %s, got %d", id, len(data.Users))
+	}
+	return data.Users[0], nil
+}
+
+// GetUsers returns the repository's user accounts
+func (api *EPrintsAPI) GetUsers() ([]*User, error) {
+	ids, err := api.ListUserIDs()
+	if err != nil {
+		return nil, err
+	}
+	users := []*User{}
+	for _, id := range ids {
+		user, err := api.GetUser(id)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
@@ -0,0 +1,70 @@
+package eprinttools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubjectsAndUsers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/subject/":
+			fmt.Fprintf(w, `<html><body><ul><li><a href="ROOT.xml">ROOT.xml</a></li><li><a href="ChemEng.xml">ChemEng.xml</a></li></ul></body></html>`)
+		case "/rest/subject/ROOT.xml":
+			fmt.Fprintf(w, `<subjects><subject><subjectid>ROOT</subjectid><depositable>FALSE</depositable></subject></subjects>`)
+		case "/rest/subject/ChemEng.xml":
+			fmt.Fprintf(w, `<subjects><subject><subjectid>ChemEng</subjectid>
+<name><item><name>Chemical Engineering</name><lang>en</lang></item></name>
+<parents><item>ROOT</item></parents><ancestors><item>ROOT</item><item>ChemEng</item></ancestors>
+<depositable>TRUE</depositable></subject></subjects>`)
+		case "/rest/user/":
+			fmt.Fprintf(w, `<html><body><ul><li><a href="1.xml">1.xml</a></li></ul></body></html>`)
+		case "/rest/user/1.xml":
+			fmt.Fprintf(w, `<users><user><userid>1</userid><username>admin</username><usertype>admin</usertype>
+<name><family>Doe</family><given>Jane</given></name><email>jane@example.edu</email></user></users>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	api, err := New(ts.URL, false, "", "", "")
+	if err != nil {
+		t.Errorf("Failed to create new api, %s", err)
+		t.FailNow()
+	}
+	subjects, err := api.GetSubjects()
+	if err != nil {
+		t.Errorf("GetSubjects() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(subjects) != 2 {
+		t.Errorf("expected two subjects, got %+v", subjects)
+	}
+	if subject, ok := subjects["ChemEng"]; ok == false {
+		t.Errorf("expected ChemEng subject")
+	} else {
+		if subject.Label("en") != "Chemical Engineering" {
+			t.Errorf("unexpected label %q", subject.Label("en"))
+		}
+		if len(subject.Parents) != 1 || subject.Parents[0] != "ROOT" {
+			t.Errorf("unexpected parents %+v", subject.Parents)
+		}
+	}
+	if label := subjects["ROOT"].Label("en"); label != "ROOT" {
+		t.Errorf("expected subject id as label, got %q", label)
+	}
+	users, err := api.GetUsers()
+	if err != nil {
+		t.Errorf("GetUsers() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(users) != 1 || users[0].Username != "admin" || users[0].Name.Family != "Doe" {
+		t.Errorf("unexpected users %+v", users)
+	}
+	if _, err := api.GetSubject("missing"); err == nil {
+		t.Errorf("expected an error for a missing subject")
+	}
+}
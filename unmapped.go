@@ -0,0 +1,89 @@
+package eprinttools
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// mappedEPrintFields returns the set of element names the EPrint struct
+// knows how to unmarshal (e.g. "title", "creators", "documents").
+func mappedEPrintFields() map[string]bool {
+	m := map[string]bool{}
+	t := reflect.TypeOf(EPrint{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("xml")
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if len(parts) > 1 && parts[1] == "attr" {
+			continue
+		}
+		if i := strings.Index(name, ">"); i > -1 {
+			name = name[0:i]
+		}
+		m[name] = true
+	}
+	return m
+}
+
+// UnmappedFields scans EPrint XML and reports the elements of each
+// eprint record that are not mapped by the EPrint struct and would be
+// silently dropped when unmarshaled. The result is a map of eprint id
+// to the list of unmapped element names. Records without unmapped
+// elements are not included.
+func UnmappedFields(src []byte) (map[string][]string, error) {
+	var (
+		eprintID string
+		unmapped []string
+	)
+	known := mappedEPrintFields()
+	results := map[string][]string{}
+	dec := xml.NewDecoder(bytes.NewReader(toValidUTF8(src)))
+	dec.CharsetReader = charsetReader
+	dec.Entity = xml.HTMLEntity
+	depth, cnt := 0, 0
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local == "eprint":
+				cnt++
+				eprintID, unmapped = fmt.Sprintf("%d", cnt), []string{}
+			case depth == 3 && t.Name.Local == "eprintid":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return results, err
+				}
+				eprintID = strings.TrimSpace(s)
+				depth--
+			case depth == 3:
+				if _, ok := known[t.Name.Local]; ok == false {
+					unmapped = append(unmapped, t.Name.Local)
+				}
+				if err := dec.Skip(); err != nil {
+					return results, err
+				}
+				depth--
+			}
+		case xml.EndElement:
+			if depth == 2 && t.Name.Local == "eprint" && len(unmapped) > 0 {
+				results[eprintID] = unmapped
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package eprinttools
+
+import (
+	"testing"
+)
+
+func TestUnmappedFields(t *testing.T) {
+	src := []byte(`<?xml version='1.0' encoding='utf-8'?>
+<eprints xmlns='http://eprints.org/ep2/data/2.0'>
+  <eprint id='https://example.org/id/eprint/1'>
+    <eprintid>1</eprintid>
+    <documents><document><docid>2</docid></document></documents>
+    <title>A mapped title</title>
+    <option_minor><item>physics</item></option_minor>
+    <patent_filing_date>2020-01-01</patent_filing_date>
+  </eprint>
+  <eprint id='https://example.org/id/eprint/3'>
+    <eprintid>3</eprintid>
+    <title>Nothing unmapped</title>
+  </eprint>
+</eprints>`)
+	unmapped, err := UnmappedFields(src)
+	if err != nil {
+		t.Errorf("UnmappedFields() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(unmapped) != 1 {
+		t.Errorf("expected one record with unmapped fields, got %+v", unmapped)
+		t.FailNow()
+	}
+	fields, ok := unmapped["1"]
+	if ok == false {
+		t.Errorf("expected unmapped fields for eprint 1, got %+v", unmapped)
+		t.FailNow()
+	}
+	expected := []string{"option_minor", "patent_filing_date"}
+	if len(fields) != len(expected) {
+		t.Errorf("expected %+v, got %+v", expected, fields)
+		t.FailNow()
+	}
+	for i, name := range expected {
+		if fields[i] != name {
+			t.Errorf("expected %q, got %q", name, fields[i])
+		}
+	}
+}
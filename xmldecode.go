@@ -0,0 +1,233 @@
+package eprinttools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	// Golang optional libraries
+	"golang.org/x/text/encoding/charmap"
+)
+
+// latin1Reader converts ISO-8859-1 encoded bytes read from r to UTF-8.
+// If all is false only the bytes that are not valid UTF-8 are converted
+// and they are treated as Windows-1252 (see toValidUTF8).
+type latin1Reader struct {
+	r   *bufio.Reader
+	all bool
+	buf []byte
+}
+
+// Read implements io.Reader
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	var (
+		rn  rune
+		err error
+		enc [utf8.UTFMax]byte
+	)
+	for len(l.buf) < len(p) {
+		if l.all {
+			var b byte
+			b, err = l.r.ReadByte()
+			rn = rune(b)
+		} else {
+			var size int
+			rn, size, err = l.r.ReadRune()
+			if err == nil && rn == utf8.RuneError && size == 1 {
+				l.r.UnreadRune()
+				var b byte
+				b, err = l.r.ReadByte()
+				rn = charmap.Windows1252.DecodeByte(b)
+			}
+		}
+		if err != nil {
+			break
+		}
+		n := utf8.EncodeRune(enc[:], rn)
+		l.buf = append(l.buf, enc[:n]...)
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	if n == 0 && err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// isLatin1 returns true if charset names ISO-8859-1
+func isLatin1(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "latin-1", "l1":
+		return true
+	}
+	return false
+}
+
+// declaredCharset returns the encoding given in the XML declaration
+// (e.g. <?xml version="1.0" encoding="ISO-8859-1"?>) or an empty string.
+func declaredCharset(src []byte) string {
+	src = bytes.TrimSpace(src)
+	if bytes.HasPrefix(src, []byte("<?xml")) == false {
+		return ""
+	}
+	end := bytes.Index(src, []byte("?>"))
+	if end < 0 {
+		return ""
+	}
+	decl := string(src[:end])
+	i := strings.Index(decl, "encoding=")
+	if i < 0 {
+		return ""
+	}
+	val := strings.TrimSpace(decl[i+len("encoding="):])
+	if len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+		if j := strings.IndexByte(val[1:], val[0]); j >= 0 {
+			return val[1 : j+1]
+		}
+	}
+	return ""
+}
+
+// charsetReader is used by the XML decoder when a document declares
+// an encoding other than UTF-8. Legacy EPrints exports are sometimes
+// declared as ISO-8859-1.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch {
+	case isLatin1(charset):
+		return &latin1Reader{r: bufio.NewReader(input), all: true}, nil
+	case strings.ToLower(charset) == "us-ascii" || strings.ToLower(charset) == "ascii":
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+}
+
+// toValidUTF8 replaces any bytes that are not valid UTF-8 by treating
+// them as Windows-1252 characters. This recovers the common case of a
+// record declared as UTF-8 that contains stray Latin-1 bytes or
+// Windows "smart quotes" (e.g. 0x93 and 0x94).
+func toValidUTF8(src []byte) []byte {
+	if utf8.Valid(src) || isLatin1(declaredCharset(src)) {
+		return src
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(src)+(len(src)/10)))
+	for len(src) > 0 {
+		r, size := utf8.DecodeRune(src)
+		if r == utf8.RuneError && size == 1 {
+			out.WriteRune(charmap.Windows1252.DecodeByte(src[0]))
+		} else {
+			out.Write(src[:size])
+		}
+		src = src[size:]
+	}
+	return out.Bytes()
+}
+
+// DecodeXML unmarshals EPrint XML into v like xml.Unmarshal() but is
+// tolerant of the problems found in legacy records. Documents
+// declaring ISO-8859-1 are converted, stray non UTF-8 bytes are
+// treated as Latin-1 and HTML entities (e.g. &nbsp;, &eacute;) are
+// understood.
+func DecodeXML(src []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(toValidUTF8(src)))
+	dec.CharsetReader = charsetReader
+	dec.Entity = xml.HTMLEntity
+	return dec.Decode(v)
+}
+
+// recordingReader keeps a copy of the bytes read from r so the source
+// of an element can be sliced out using the decoder's input offsets.
+// base is the offset of buf[0].
+type recordingReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+// Read implements io.Reader
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// slice returns a copy of the bytes from offset start to end and
+// forgets the bytes before end.
+func (rr *recordingReader) slice(start, end int64) []byte {
+	src := append([]byte{}, rr.buf[start-rr.base:end-rr.base]...)
+	rr.buf = append([]byte{}, rr.buf[end-rr.base:]...)
+	rr.base = end
+	return src
+}
+
+// DecodeXMLStream reads EPrint XML from r calling fn with each eprint
+// element as it is decoded so large exports (e.g. an admin export of
+// the whole repository) don't need to be held in memory. It is
+// tolerant of the same problems as DecodeXML. If fn returns an error
+// decoding stops and the error is returned.
+func DecodeXMLStream(r io.Reader, fn func(*EPrint) error) error {
+	return decodeXMLStream(r, nil, fn)
+}
+
+// DecodeXMLStreamCustomFields works like DecodeXMLStream but also
+// sets each EPrint's CustomFields using selectors (see CustomFields()).
+func DecodeXMLStreamCustomFields(r io.Reader, selectors map[string]string, fn func(*EPrint) error) error {
+	return decodeXMLStream(r, selectors, fn)
+}
+
+// decodeXMLStream implements DecodeXMLStream and
+// DecodeXMLStreamCustomFields
+func decodeXMLStream(r io.Reader, selectors map[string]string, fn func(*EPrint) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	//NOTE: Peek may return fewer bytes than asked for at EOF,
+	// that's enough to find the XML declaration.
+	head, _ := br.Peek(1024)
+	//NOTE: Latin-1 is converted here rather than by the decoder's
+	// CharsetReader so the recorded bytes match the decoder's offsets.
+	var in io.Reader = &latin1Reader{r: br, all: isLatin1(declaredCharset(head))}
+	var rec *recordingReader
+	if len(selectors) > 0 {
+		rec = &recordingReader{r: in}
+		in = rec
+	}
+	dec := xml.NewDecoder(in)
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if isLatin1(charset) {
+			return input, nil
+		}
+		return charsetReader(charset, input)
+	}
+	dec.Entity = xml.HTMLEntity
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok == true && start.Name.Local == "eprint" {
+			e := new(EPrint)
+			if err := dec.DecodeElement(e, &start); err != nil {
+				return err
+			}
+			if rec != nil {
+				fields, err := CustomFields(rec.slice(offset, dec.InputOffset()), selectors)
+				if err != nil {
+					return err
+				}
+				if len(fields) > 0 && len(fields[0]) > 0 {
+					e.CustomFields = fields[0]
+				}
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
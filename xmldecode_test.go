@@ -0,0 +1,122 @@
+package eprinttools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeXML(t *testing.T) {
+	expected := "Café naïve\u00a0study"
+
+	// Declared UTF-8 with a stray Latin-1 byte and HTML entities
+	src := []byte("<?xml version='1.0' encoding='utf-8'?>\n<eprints><eprint><title>Caf\xe9 na&iuml;ve&nbsp;study</title></eprint></eprints>")
+	eprints := new(EPrints)
+	if err := DecodeXML(src, &eprints); err != nil {
+		t.Errorf("DecodeXML() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(eprints.EPrint) != 1 {
+		t.Errorf("expected one eprint, got %d", len(eprints.EPrint))
+		t.FailNow()
+	}
+	if eprints.EPrint[0].Title != expected {
+		t.Errorf("expected %q, got %q", expected, eprints.EPrint[0].Title)
+	}
+
+	// Declared ISO-8859-1
+	src = []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<eprints><eprint><title>Caf\xe9 na\xefve\xa0study</title></eprint></eprints>")
+	eprints = new(EPrints)
+	if err := DecodeXML(src, &eprints); err != nil {
+		t.Errorf("DecodeXML() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(eprints.EPrint) != 1 {
+		t.Errorf("expected one eprint, got %d", len(eprints.EPrint))
+		t.FailNow()
+	}
+	if eprints.EPrint[0].Title != expected {
+		t.Errorf("expected %q, got %q", expected, eprints.EPrint[0].Title)
+	}
+}
+
+func TestDecodeXMLStream(t *testing.T) {
+	expected := []string{"Café naïve\u00a0study", "Second"}
+	for _, src := range []string{
+		"<?xml version='1.0' encoding='utf-8'?>\n<eprints><eprint><title>Caf\xe9 na&iuml;ve&nbsp;study</title></eprint><eprint><title>Second</title></eprint></eprints>",
+		"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<eprints><eprint><title>Caf\xe9 na\xefve\xa0study</title></eprint><eprint><title>Second</title></eprint></eprints>",
+	} {
+		titles := []string{}
+		err := DecodeXMLStream(strings.NewReader(src), func(e *EPrint) error {
+			titles = append(titles, e.Title)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("DecodeXMLStream() returned an error, %s", err)
+			continue
+		}
+		if strings.Join(titles, "|") != strings.Join(expected, "|") {
+			t.Errorf("expected %q, got %q", expected, titles)
+		}
+	}
+}
+
+func TestDecodeXMLStreamCustomFields(t *testing.T) {
+	selectors := map[string]string{
+		"patent_filing_date": "/patent_filing_date",
+	}
+	for _, src := range []string{
+		"<?xml version='1.0' encoding='utf-8'?>\n<eprints><eprint><eprintid>1</eprintid><title>Caf\xe9</title><patent_filing_date>2020-01-01</patent_filing_date></eprint>\n<eprint><eprintid>2</eprintid><title>Second</title></eprint></eprints>",
+		"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<eprints><eprint><eprintid>1</eprintid><title>Caf\xe9</title><patent_filing_date>2020-01-01</patent_filing_date></eprint>\n<eprint><eprintid>2</eprintid><title>Second</title></eprint></eprints>",
+	} {
+		eprints := []*EPrint{}
+		err := DecodeXMLStreamCustomFields(strings.NewReader(src), selectors, func(e *EPrint) error {
+			eprints = append(eprints, e)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("DecodeXMLStreamCustomFields() returned an error, %s", err)
+			continue
+		}
+		if len(eprints) != 2 {
+			t.Errorf("expected 2 eprints, got %d", len(eprints))
+			continue
+		}
+		if eprints[0].Title != "Café" {
+			t.Errorf("expected title %q, got %q", "Café", eprints[0].Title)
+		}
+		if s, ok := eprints[0].CustomFields["patent_filing_date"]; ok == false || s != "2020-01-01" {
+			t.Errorf("expected patent_filing_date 2020-01-01, got %+v", eprints[0].CustomFields)
+		}
+		if eprints[1].CustomFields != nil {
+			t.Errorf("expected no custom fields for eprint 2, got %+v", eprints[1].CustomFields)
+		}
+	}
+}
+
+func TestDecodeXMLWindows1252(t *testing.T) {
+	expected := "A “quoted” café"
+
+	// Declared UTF-8 with stray Windows-1252 smart quotes
+	src := "<?xml version='1.0' encoding='utf-8'?>\n<eprints><eprint><title>A \x93quoted\x94 caf\xe9</title></eprint></eprints>"
+	eprints := new(EPrints)
+	if err := DecodeXML([]byte(src), &eprints); err != nil {
+		t.Errorf("DecodeXML() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(eprints.EPrint) != 1 || eprints.EPrint[0].Title != expected {
+		t.Errorf("expected %q, got %+v", expected, eprints.EPrint)
+	}
+
+	titles := []string{}
+	err := DecodeXMLStream(strings.NewReader(src), func(e *EPrint) error {
+		titles = append(titles, e.Title)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("DecodeXMLStream() returned an error, %s", err)
+		t.FailNow()
+	}
+	if len(titles) != 1 || titles[0] != expected {
+		t.Errorf("expected %q, got %q", expected, titles)
+	}
+}